@@ -0,0 +1,164 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon/history"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forks"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"sort"
+)
+
+var (
+	ErrPrunedSlot = errors.New("slot is before the earliest snapshot")
+	ErrFutureSlot = errors.New("slot is after the latest archived block")
+)
+
+// Archive of a single chain: all blocks are kept, but only a snapshot state every SnapshotInterval slots.
+// Other historical states are regenerated on demand, by replaying the blocks from the nearest snapshot.
+// Not safe for concurrent use.
+type Archive struct {
+	SnapshotInterval Slot
+	// The forks to upgrade regenerated states with, at the fork epochs. Nil for a chain with only the phase0 fork.
+	Forks *forks.Registry
+
+	// Snapshots of the chain, ordered by slot. The first snapshot is the anchor state.
+	snapshots []*phase0.BeaconState
+	// Blocks after the anchor, ordered by slot
+	blocks []*phase0.SignedBeaconBlock
+	// Roots of the blocks, including the anchor block root at index 0
+	blockRoots []Root
+	byRoot     map[Root]*phase0.SignedBeaconBlock
+	// Historical batches by historical root index, loaded when block roots are proven
+	batches map[uint64]*history.HistoricalBatch
+}
+
+// Creates an archive anchored at the given state, e.g. the genesis state or a checkpoint state.
+// Historical states before the anchor cannot be regenerated. The anchor state is copied.
+func NewArchive(anchor *phase0.FullFeaturedState, snapshotInterval Slot) (*Archive, error) {
+	if snapshotInterval == 0 {
+		return nil, errors.New("snapshot interval must be at least one slot")
+	}
+	snapshot, err := anchor.Copy()
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{
+		SnapshotInterval: snapshotInterval,
+		snapshots:        []*phase0.BeaconState{snapshot.BeaconState},
+		blockRoots:       []Root{anchor.HeadRoot()},
+		byRoot:           make(map[Root]*phase0.SignedBeaconBlock),
+		batches:          make(map[uint64]*history.HistoricalBatch),
+	}, nil
+}
+
+// The root of the latest archived block, the anchor block root if no blocks were added yet.
+func (a *Archive) HeadRoot() Root {
+	return a.blockRoots[len(a.blockRoots)-1]
+}
+
+// The slot of the latest archived block, the anchor slot if no blocks were added yet.
+func (a *Archive) HeadSlot() Slot {
+	if len(a.blocks) == 0 {
+		return a.snapshots[0].Slot
+	}
+	return a.blocks[len(a.blocks)-1].Message.Slot
+}
+
+// Adds the next block of the chain, with its post-state, after the block was verified, e.g. by the fork choice.
+// The post-state is copied if it is stored as snapshot.
+func (a *Archive) AddBlock(block *phase0.SignedBeaconBlock, post *phase0.FullFeaturedState) error {
+	if slot := block.Message.Slot; slot <= a.HeadSlot() {
+		return fmt.Errorf("block at slot %d does not follow the head at slot %d", slot, a.HeadSlot())
+	}
+	if parent := a.HeadRoot(); block.Message.ParentRoot != parent {
+		return fmt.Errorf("block parent root %x does not match the head root %x", block.Message.ParentRoot, parent)
+	}
+	if post.Slot != block.Message.Slot || post.StateRoot() != block.Message.StateRoot {
+		return errors.New("state is not the post-state of the block")
+	}
+	root := ssz.HashTreeRoot(&block.Message, phase0.BeaconBlockSSZ)
+	if last := a.snapshots[len(a.snapshots)-1]; post.Slot >= last.Slot+a.SnapshotInterval {
+		snapshot, err := post.Copy()
+		if err != nil {
+			return err
+		}
+		a.snapshots = append(a.snapshots, snapshot.BeaconState)
+	}
+	a.blocks = append(a.blocks, block)
+	a.blockRoots = append(a.blockRoots, root)
+	a.byRoot[root] = block
+	return nil
+}
+
+// The root of the latest block at or before the given slot.
+func (a *Archive) BlockRootAt(slot Slot) (Root, error) {
+	if slot < a.snapshots[0].Slot {
+		return Root{}, ErrPrunedSlot
+	}
+	if slot > a.HeadSlot() {
+		return Root{}, ErrFutureSlot
+	}
+	i := sort.Search(len(a.blocks), func(i int) bool {
+		return a.blocks[i].Message.Slot > slot
+	})
+	// the anchor block root is at index 0, the root of block i at index i+1
+	return a.blockRoots[i], nil
+}
+
+// Regenerates the state at the given slot, by replaying the blocks from the nearest snapshot.
+// Empty slots are processed up to the given slot, it must not be after the latest archived block.
+func (a *Archive) StateAt(slot Slot) (*phase0.FullFeaturedState, error) {
+	if slot > a.HeadSlot() {
+		return nil, ErrFutureSlot
+	}
+	i := sort.Search(len(a.snapshots), func(i int) bool {
+		return a.snapshots[i].Slot > slot
+	}) - 1
+	if i < 0 {
+		return nil, ErrPrunedSlot
+	}
+	state, err := phase0.NewFullFeaturedState(a.snapshots[i]).Copy()
+	if err != nil {
+		return nil, err
+	}
+	start := sort.Search(len(a.blocks), func(j int) bool {
+		return a.blocks[j].Message.Slot > state.Slot
+	})
+	for _, b := range a.blocks[start:] {
+		if b.Message.Slot > slot {
+			break
+		}
+		// The block signature was verified before the block was archived, the state root is still checked.
+		state, err = a.Forks.Phase0StateTransition(state, b, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to replay block at slot %d: %v", b.Message.Slot, err)
+		}
+	}
+	return a.Forks.ProcessPhase0Slots(state, slot)
+}
+
+// Blocks with a slot in the range [start, start+count), ordered by slot. See chainsync.BlockSource.
+func (a *Archive) BlocksByRange(start Slot, count uint64) ([]*phase0.SignedBeaconBlock, error) {
+	i := sort.Search(len(a.blocks), func(i int) bool {
+		return a.blocks[i].Message.Slot >= start
+	})
+	out := make([]*phase0.SignedBeaconBlock, 0)
+	for _, b := range a.blocks[i:] {
+		if uint64(b.Message.Slot-start) >= count {
+			break
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// The archived block with the given root, or phase0.ErrUnknownBlock. See chainsync.BlockSource.
+func (a *Archive) BlockByRoot(root Root) (*phase0.SignedBeaconBlock, error) {
+	if b, ok := a.byRoot[root]; ok {
+		return b, nil
+	}
+	return nil, phase0.ErrUnknownBlock
+}
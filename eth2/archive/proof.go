@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon/history"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zssz/htr"
+	zmerkle "github.com/protolambda/zssz/merkle"
+)
+
+// Proof of the block root at a historical slot, against the historical root of the batch that includes the slot.
+type BlockRootProof struct {
+	Slot      Slot
+	BlockRoot Root
+	// Merkle branch of the block root in the historical batch, bottom-up.
+	// The last node is the root of the state roots of the batch.
+	Branch []Root
+}
+
+// The index of the historical root that the proof is verified against.
+func (p *BlockRootProof) HistoricalRootIndex() uint64 {
+	return uint64(p.Slot / SLOTS_PER_HISTORICAL_ROOT)
+}
+
+// Verifies the proof against the historical roots of a state, e.g. of a trusted recent state.
+func (p *BlockRootProof) Verify(historicalRoots history.HistoricalRoots) bool {
+	index := p.HistoricalRootIndex()
+	if index >= uint64(len(historicalRoots)) {
+		return false
+	}
+	// the block roots are the first field of the batch, the index in the batch is the index in the block roots
+	depth := uint64(zmerkle.GetDepth(uint64(SLOTS_PER_HISTORICAL_ROOT))) + 1
+	if uint64(len(p.Branch)) != depth {
+		return false
+	}
+	return merkle.VerifyMerkleBranch(p.BlockRoot, p.Branch, depth,
+		uint64(p.Slot%SLOTS_PER_HISTORICAL_ROOT), historicalRoots[index])
+}
+
+// The historical batch of the given historical root index, regenerated from the state at the end of the batch.
+func (a *Archive) historicalBatch(index uint64) (*history.HistoricalBatch, error) {
+	if batch, ok := a.batches[index]; ok {
+		return batch, nil
+	}
+	// After the batch is added to the historical roots, the block and state roots are only
+	// overwritten when the next slot is processed: the state at the first slot of the next batch has them.
+	state, err := a.StateAt(Slot(index+1) * SLOTS_PER_HISTORICAL_ROOT)
+	if err != nil {
+		return nil, fmt.Errorf("cannot regenerate historical batch %d: %v", index, err)
+	}
+	batch := &history.HistoricalBatch{
+		BlockRoots: state.BlockRoots,
+		StateRoots: state.StateRoots,
+	}
+	if index >= uint64(len(state.HistoricalRoots)) {
+		return nil, fmt.Errorf("historical batch %d is not in the historical roots, the chain does not start at genesis", index)
+	}
+	if root := ssz.HashTreeRoot(batch, history.HistoricalBatchSSZ); root != state.HistoricalRoots[index] {
+		return nil, fmt.Errorf("regenerated historical batch %d root %x does not match historical root %x",
+			index, root, state.HistoricalRoots[index])
+	}
+	a.batches[index] = batch
+	return batch, nil
+}
+
+// Proves the block root at the given slot, against the historical roots.
+// Only slots of batches that were added to the historical roots can be proven.
+func (a *Archive) ProveBlockRoot(slot Slot) (*BlockRootProof, error) {
+	proof := &BlockRootProof{Slot: slot}
+	batch, err := a.historicalBatch(proof.HistoricalRootIndex())
+	if err != nil {
+		return nil, err
+	}
+	hFn := htr.HashFn(hashing.GetHashFn())
+	leaf := func(i uint64) []byte {
+		return batch.BlockRoots[i][:]
+	}
+	count := uint64(SLOTS_PER_HISTORICAL_ROOT)
+	for _, node := range zmerkle.ConstructProof(hFn, count, count, leaf, uint64(slot%SLOTS_PER_HISTORICAL_ROOT)) {
+		proof.Branch = append(proof.Branch, node)
+	}
+	stateRoots := zmerkle.Merkleize(hFn, count, count, func(i uint64) []byte {
+		return batch.StateRoots[i][:]
+	})
+	proof.Branch = append(proof.Branch, stateRoots)
+	proof.BlockRoot = batch.BlockRoots[slot%SLOTS_PER_HISTORICAL_ROOT]
+	return proof, nil
+}
@@ -0,0 +1,91 @@
+package attestations
+
+import (
+	"encoding/binary"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zssz"
+)
+
+var AggregateAndProofSSZ = zssz.GetSSZ((*AggregateAndProof)(nil))
+
+// Aggregate and proof, as defined in the v0.11 validator spec.
+type AggregateAndProof struct {
+	AggregatorIndex ValidatorIndex
+	Aggregate       Attestation
+	// Signature of the slot, selecting the validator as aggregator
+	SelectionProof BLSSignature
+}
+
+var SignedAggregateAndProofSSZ = zssz.GetSSZ((*SignedAggregateAndProof)(nil))
+
+type SignedAggregateAndProof struct {
+	Message   AggregateAndProof
+	Signature BLSSignature
+}
+
+var SelectionProofSlotSSZ = zssz.GetSSZ((*Slot)(nil))
+
+// Checks if the selection proof selects the validator as aggregator of a committee of the given size.
+func IsAggregator(committeeSize uint64, selectionProof BLSSignature) bool {
+	modulo := committeeSize / TARGET_AGGREGATORS_PER_COMMITTEE
+	if modulo < 1 {
+		modulo = 1
+	}
+	h := hashing.Hash(selectionProof[:])
+	return binary.LittleEndian.Uint64(h[:8])%modulo == 0
+}
+
+type AggregationFeature struct {
+	Meta interface {
+		meta.Versioning
+		meta.Pubkeys
+		meta.BeaconCommittees
+	}
+}
+
+func (f *AggregationFeature) selectionProofDomain(slot Slot) BLSDomain {
+	return f.Meta.GetDomain(DOMAIN_SELECTION_PROOF, slot.ToEpoch())
+}
+
+// Sign the slot with the secret key of a validator, to prove selection as aggregator.
+func (f *AggregationFeature) SignSelectionProof(secretKey [32]byte, slot Slot) BLSSignature {
+	return bls.BlsSign(secretKey, ssz.HashTreeRoot(slot, SelectionProofSlotSSZ), f.selectionProofDomain(slot))
+}
+
+// Verify the selection proof of the slot by the validator with the given index.
+func (f *AggregationFeature) VerifySelectionProof(index ValidatorIndex, slot Slot, selectionProof BLSSignature) bool {
+	return bls.BlsVerify(
+		f.Meta.Pubkey(index),
+		ssz.HashTreeRoot(slot, SelectionProofSlotSSZ),
+		selectionProof,
+		f.selectionProofDomain(slot))
+}
+
+// Checks if the selection proof selects the validator as aggregator of the committee at the slot and committee index.
+func (f *AggregationFeature) IsAggregatorOf(slot Slot, index CommitteeIndex, selectionProof BLSSignature) bool {
+	return IsAggregator(uint64(len(f.Meta.GetBeaconCommittee(slot, index))), selectionProof)
+}
+
+// Sign the aggregate and proof with the secret key of the aggregator.
+func (f *AggregationFeature) SignAggregateAndProof(secretKey [32]byte, msg *AggregateAndProof) *SignedAggregateAndProof {
+	return &SignedAggregateAndProof{
+		Message: *msg,
+		Signature: bls.BlsSign(secretKey,
+			ssz.HashTreeRoot(msg, AggregateAndProofSSZ),
+			f.Meta.GetDomain(DOMAIN_AGGREGATE_AND_PROOF, msg.Aggregate.Data.Target.Epoch)),
+	}
+}
+
+// Verify the signature of the aggregator over the aggregate and proof.
+func (f *AggregationFeature) VerifyAggregateAndProofSignature(signed *SignedAggregateAndProof) bool {
+	msg := &signed.Message
+	return bls.BlsVerify(
+		f.Meta.Pubkey(msg.AggregatorIndex),
+		ssz.HashTreeRoot(msg, AggregateAndProofSSZ),
+		signed.Signature,
+		f.Meta.GetDomain(DOMAIN_AGGREGATE_AND_PROOF, msg.Aggregate.Data.Target.Epoch))
+}
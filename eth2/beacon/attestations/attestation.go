@@ -12,6 +12,7 @@ import (
 type AttestationProcessor interface {
 	ProcessAttestations(ops []Attestation) error
 	ProcessAttestation(attestation *Attestation) error
+	ValidateAttestation(attestation *Attestation) error
 }
 
 type AttestationFeature struct {
@@ -30,7 +31,7 @@ type AttestationFeature struct {
 func (f *AttestationFeature) ProcessAttestations(ops []Attestation) error {
 	for i := range ops {
 		if err := f.ProcessAttestation(&ops[i]); err != nil {
-			return err
+			return WithOperationIndex(err, i)
 		}
 	}
 	return nil
@@ -44,16 +45,17 @@ type Attestation struct {
 	Signature       BLSSignature
 }
 
-func (f *AttestationFeature) ProcessAttestation(attestation *Attestation) error {
+// Checks if the attestation is valid to include in a block on top of the current state, without changing the state.
+func (f *AttestationFeature) ValidateAttestation(attestation *Attestation) error {
 	data := &attestation.Data
 
 	// Check slot
 	currentSlot := f.Meta.CurrentSlot()
 	if !(currentSlot <= data.Slot+SLOTS_PER_EPOCH) {
-		return errors.New("attestation slot is too old")
+		return NewOperationError(AttestationOperation, AttestationSlotTooOld, nil)
 	}
 	if !(data.Slot+MIN_ATTESTATION_INCLUSION_DELAY <= currentSlot) {
-		return errors.New("attestation is too new")
+		return NewOperationError(AttestationOperation, AttestationSlotTooNew, nil)
 	}
 
 	currentEpoch := f.Meta.CurrentEpoch()
@@ -61,38 +63,50 @@ func (f *AttestationFeature) ProcessAttestation(attestation *Attestation) error
 
 	// Check target
 	if data.Target.Epoch < previousEpoch {
-		return errors.New("attestation data is invalid, target is too far in past")
+		return NewOperationError(AttestationOperation, AttestationTargetTooOld, nil)
 	} else if data.Target.Epoch > currentEpoch {
-		return errors.New("attestation data is invalid, target is in future")
+		return NewOperationError(AttestationOperation, AttestationTargetInFuture, nil)
 	}
 	// And if it matches the slot
 	if data.Target.Epoch != data.Slot.ToEpoch() {
-		return errors.New("attestation data is invalid, slot epoch does not match target epoch")
+		return NewOperationError(AttestationOperation, AttestationTargetEpochMismatch, nil)
 	}
 
 	// Check committee index
 	if uint64(data.Index) >= f.Meta.GetCommitteeCountAtSlot(data.Slot) {
-		return errors.New("attestation data is invalid, committee index out of range")
+		return NewOperationError(AttestationOperation, AttestationCommitteeIndexOutOfRange, nil)
 	}
 
 	// Check source
 	if data.Target.Epoch == currentEpoch {
 		if data.Source != f.Meta.CurrentJustified() {
-			return errors.New("attestation source does not match current justified checkpoint")
+			return NewOperationError(AttestationOperation, AttestationSourceMismatch,
+				errors.New("source does not match current justified checkpoint"))
 		}
 	} else {
 		if data.Source != f.Meta.PreviousJustified() {
-			return errors.New("attestation source does not match previous justified checkpoint")
+			return NewOperationError(AttestationOperation, AttestationSourceMismatch,
+				errors.New("source does not match previous justified checkpoint"))
 		}
 	}
 
 	// Check signature and bitfields
 	committee := f.Meta.GetBeaconCommittee(data.Slot, data.Index)
 	if indexedAtt, err := attestation.ConvertToIndexed(committee); err != nil {
-		return fmt.Errorf("attestation could not be converted to an indexed attestation: %v", err)
+		return NewOperationError(AttestationOperation, AttestationBitsSizeMismatch, err)
 	} else if err := indexedAtt.Validate(f.Meta); err != nil {
-		return fmt.Errorf("attestation could not be verified in its indexed form: %v", err)
+		return NewOperationError(AttestationOperation, AttestationInvalidIndexed, err)
 	}
+	return nil
+}
+
+func (f *AttestationFeature) ProcessAttestation(attestation *Attestation) error {
+	if err := f.ValidateAttestation(attestation); err != nil {
+		return err
+	}
+	data := &attestation.Data
+	currentSlot := f.Meta.CurrentSlot()
+	currentEpoch := f.Meta.CurrentEpoch()
 
 	// Cache pending attestation
 	pendingAttestation := &PendingAttestation{
@@ -113,7 +127,7 @@ func (f *AttestationFeature) ProcessAttestation(attestation *Attestation) error
 func (attestation *Attestation) ConvertToIndexed(committee []ValidatorIndex) (*IndexedAttestation, error) {
 	bitLen := attestation.AggregationBits.BitLen()
 	if uint64(len(committee)) != bitLen {
-		return nil, fmt.Errorf("committee size does not match bits size: %d <> %d", len(committee), bitLen)
+		return nil, fmt.Errorf("%w: %d <> %d", AttestationBitsSizeMismatch, len(committee), bitLen)
 	}
 
 	participants := make([]ValidatorIndex, 0, len(committee))
@@ -3,6 +3,7 @@ package attestations
 import (
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
+	"github.com/protolambda/zrnt/eth2/util/parallel"
 )
 
 type AttesterStatusFeature struct {
@@ -16,74 +17,111 @@ type AttesterStatusFeature struct {
 		meta.SlashedIndices
 		meta.ActiveIndices
 		meta.ValidatorEpochData
+		meta.Concurrency
 	}
 }
 
+type committeeKey struct {
+	Slot  Slot
+	Index CommitteeIndex
+}
+
+// Groups the attestations by committee, keeping the original order within each group.
+func groupByCommittee(attestations []*PendingAttestation) (out [][]*PendingAttestation) {
+	groups := make(map[committeeKey]int)
+	for _, att := range attestations {
+		key := committeeKey{Slot: att.Data.Slot, Index: att.Data.Index}
+		if i, ok := groups[key]; ok {
+			out[i] = append(out[i], att)
+		} else {
+			groups[key] = len(out)
+			out = append(out, []*PendingAttestation{att})
+		}
+	}
+	return
+}
+
 func (f *AttesterStatusFeature) GetAttesterStatuses() (out []AttesterStatus) {
 	count := f.Meta.ValidatorCount()
+	workers := f.Meta.GetConcurrency()
 
 	currentEpoch := f.Meta.CurrentEpoch()
 	prevEpoch := f.Meta.PreviousEpoch()
 
 	out = make([]AttesterStatus, count, count)
 
-	for i := ValidatorIndex(0); i < ValidatorIndex(len(out)); i++ {
-		status := &out[i]
-		if !f.Meta.IsSlashed(i) {
-			status.Flags |= UnslashedAttester
-		}
-		if f.Meta.IsActive(i, currentEpoch) {
-			status.Flags |= EligibleAttester
-		} else if f.Meta.IsSlashed(i) && prevEpoch+1 < f.Meta.WithdrawableEpoch(i) {
-			status.Flags |= EligibleAttester
+	parallel.Range(workers, count, func(start uint64, end uint64) {
+		for i := ValidatorIndex(start); i < ValidatorIndex(end); i++ {
+			status := &out[i]
+			if !f.Meta.IsSlashed(i) {
+				status.Flags |= UnslashedAttester
+			}
+			if f.Meta.IsActive(i, currentEpoch) {
+				status.Flags |= EligibleAttester
+			} else if f.Meta.IsSlashed(i) && prevEpoch+1 < f.Meta.WithdrawableEpoch(i) {
+				status.Flags |= EligibleAttester
+			}
+			status.AttestedProposer = ValidatorIndexMarker
 		}
-		status.AttestedProposer = ValidatorIndexMarker
-	}
+	})
 
 	processEpoch := func(
 		attestations []*PendingAttestation, epoch Epoch,
 		sourceFlag, targetFlag, headFlag AttesterFlag) {
 
+		// The committees of an epoch are disjoint, attestations of different committees never touch the same status.
+		// So the attestations can be processed per committee in parallel.
+		var groups [][]*PendingAttestation
+		if workers > 1 {
+			groups = groupByCommittee(attestations)
+		} else {
+			groups = [][]*PendingAttestation{attestations}
+		}
+
 		targetBlockRoot := f.Meta.GetBlockRootAtSlot(epoch.GetStartSlot())
-		participants := make([]ValidatorIndex, 0, MAX_VALIDATORS_PER_COMMITTEE)
-		for _, att := range attestations {
-			attBlockRoot := f.Meta.GetBlockRootAtSlot(att.Data.Slot)
+		parallel.Range(workers, uint64(len(groups)), func(start uint64, end uint64) {
+			participants := make([]ValidatorIndex, 0, MAX_VALIDATORS_PER_COMMITTEE)
+			for _, group := range groups[start:end] {
+				for _, att := range group {
+					attBlockRoot := f.Meta.GetBlockRootAtSlot(att.Data.Slot)
 
-			// attestation-target is already known to be this epoch, get it from the pre-computed shuffling directly.
-			committee := f.Meta.GetBeaconCommittee(att.Data.Slot, att.Data.Index)
+					// attestation-target is already known to be this epoch, get it from the pre-computed shuffling directly.
+					committee := f.Meta.GetBeaconCommittee(att.Data.Slot, att.Data.Index)
 
-			participants = participants[:0]                   // reset old slice (re-used in for loop)
-			participants = append(participants, committee...) // add committee indices
+					participants = participants[:0]                   // reset old slice (re-used in for loop)
+					participants = append(participants, committee...) // add committee indices
 
-			if epoch == prevEpoch {
-				for _, p := range participants {
-					status := &out[p]
+					if epoch == prevEpoch {
+						for _, p := range participants {
+							status := &out[p]
 
-					// If the attestation is the earliest, i.e. has the smallest delay
-					if status.AttestedProposer == ValidatorIndexMarker || status.InclusionDelay > att.InclusionDelay {
-						status.InclusionDelay = att.InclusionDelay
-						status.AttestedProposer = att.ProposerIndex
+							// If the attestation is the earliest, i.e. has the smallest delay
+							if status.AttestedProposer == ValidatorIndexMarker || status.InclusionDelay > att.InclusionDelay {
+								status.InclusionDelay = att.InclusionDelay
+								status.AttestedProposer = att.ProposerIndex
+							}
+						}
 					}
-				}
-			}
 
-			participants = att.AggregationBits.FilterParticipants(participants) // only keep the participants
-			for _, p := range participants {
-				status := &out[p]
+					participants = att.AggregationBits.FilterParticipants(participants) // only keep the participants
+					for _, p := range participants {
+						status := &out[p]
 
-				// remember the participant as one of the good validators
-				status.Flags |= sourceFlag
+						// remember the participant as one of the good validators
+						status.Flags |= sourceFlag
 
-				// If the attestation is for the boundary:
-				if att.Data.Target.Root == targetBlockRoot {
-					status.Flags |= targetFlag
-				}
-				// If the attestation is for the head (att the time of attestation):
-				if att.Data.BeaconBlockRoot == attBlockRoot {
-					status.Flags |= headFlag
+						// If the attestation is for the boundary:
+						if att.Data.Target.Root == targetBlockRoot {
+							status.Flags |= targetFlag
+						}
+						// If the attestation is for the head (att the time of attestation):
+						if att.Data.BeaconBlockRoot == attBlockRoot {
+							status.Flags |= headFlag
+						}
+					}
 				}
 			}
-		}
+		})
 	}
 	processEpoch(f.State.PreviousEpochAttestations, prevEpoch,
 		PrevSourceAttester, PrevTargetAttester, PrevHeadAttester)
@@ -4,6 +4,7 @@ import (
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
 	"github.com/protolambda/zrnt/eth2/util/math"
+	"github.com/protolambda/zrnt/eth2/util/parallel"
 )
 
 type AttestationDeltasFeature struct {
@@ -14,6 +15,8 @@ type AttestationDeltasFeature struct {
 		meta.EffectiveBalances
 		meta.AttesterStatuses
 		meta.Finality
+		meta.Concurrency
+		meta.RewardsReporting
 	}
 }
 
@@ -33,55 +36,94 @@ func (f *AttestationDeltasFeature) AttestationDeltas() *Deltas {
 	balanceSqRoot := Gwei(math.IntegerSquareroot(uint64(totalBalance)))
 	finalityDelay := previousEpoch - f.Meta.Finalized().Epoch
 
-	for i := ValidatorIndex(0); i < validatorCount; i++ {
-		status := attesterStatuses[i]
-		if status.Flags&EligibleAttester != 0 {
-
-			effBalance := f.Meta.EffectiveBalance(i)
-			baseReward := effBalance * BASE_REWARD_FACTOR /
-				balanceSqRoot / BASE_REWARDS_PER_EPOCH
-
-			// Expected FFG source
-			if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
-				// Justification-participation reward
-				deltas.Rewards[i] += baseReward * prevEpochSourceStake / totalBalance
-
-				// Inclusion speed bonus
-				proposerReward := baseReward / PROPOSER_REWARD_QUOTIENT
-				deltas.Rewards[status.AttestedProposer] += proposerReward
-				maxAttesterReward := baseReward - proposerReward
-				deltas.Rewards[i] += maxAttesterReward / Gwei(status.InclusionDelay)
-			} else {
-				//Justification-non-participation R-penalty
-				deltas.Penalties[i] += baseReward
-			}
+	// Without a report, all components are accumulated in the combined deltas directly.
+	source, target, head, inclusion, proposerInclusion, inactivity := deltas, deltas, deltas, deltas, deltas, deltas
+	report := f.Meta.RewardsReport()
+	if report != nil {
+		source = NewDeltas(uint64(validatorCount))
+		target = NewDeltas(uint64(validatorCount))
+		head = NewDeltas(uint64(validatorCount))
+		inclusion = NewDeltas(uint64(validatorCount))
+		proposerInclusion = NewDeltas(uint64(validatorCount))
+		inactivity = NewDeltas(uint64(validatorCount))
+	}
 
-			// Expected FFG target
-			if status.Flags.HasMarkers(PrevTargetAttester | UnslashedAttester) {
-				// Boundary-attestation reward
-				deltas.Rewards[i] += baseReward * prevEpochTargetStake / totalBalance
-			} else {
-				//Boundary-attestation-non-participation R-penalty
-				deltas.Penalties[i] += baseReward
-			}
+	baseReward := func(i ValidatorIndex) Gwei {
+		return f.Meta.EffectiveBalance(i) * BASE_REWARD_FACTOR /
+			balanceSqRoot / BASE_REWARDS_PER_EPOCH
+	}
 
-			// Expected head
-			if status.Flags.HasMarkers(PrevHeadAttester | UnslashedAttester) {
-				// Canonical-participation reward
-				deltas.Rewards[i] += baseReward * prevEpochHeadStake / totalBalance
-			} else {
-				// Non-canonical-participation R-penalty
-				deltas.Penalties[i] += baseReward
-			}
+	// Every worker only changes the deltas of its own range of validators.
+	parallel.Range(f.Meta.GetConcurrency(), uint64(validatorCount), func(start uint64, end uint64) {
+		for i := ValidatorIndex(start); i < ValidatorIndex(end); i++ {
+			status := attesterStatuses[i]
+			if status.Flags&EligibleAttester != 0 {
+
+				effBalance := f.Meta.EffectiveBalance(i)
+				baseReward := baseReward(i)
+
+				// Expected FFG source
+				if status.Flags.HasMarkers(PrevSourceAttester | UnslashedAttester) {
+					// Justification-participation reward
+					source.Rewards[i] += baseReward * prevEpochSourceStake / totalBalance
+
+					// Inclusion speed bonus (the proposer part is rewarded below)
+					proposerReward := baseReward / PROPOSER_REWARD_QUOTIENT
+					maxAttesterReward := baseReward - proposerReward
+					inclusion.Rewards[i] += maxAttesterReward / Gwei(status.InclusionDelay)
+				} else {
+					//Justification-non-participation R-penalty
+					source.Penalties[i] += baseReward
+				}
 
-			// Take away max rewards if we're not finalizing
-			if finalityDelay > MIN_EPOCHS_TO_INACTIVITY_PENALTY {
-				deltas.Penalties[i] += baseReward * BASE_REWARDS_PER_EPOCH
-				if !status.Flags.HasMarkers(PrevHeadAttester | UnslashedAttester) {
-					deltas.Penalties[i] += effBalance * Gwei(finalityDelay) / INACTIVITY_PENALTY_QUOTIENT
+				// Expected FFG target
+				if status.Flags.HasMarkers(PrevTargetAttester | UnslashedAttester) {
+					// Boundary-attestation reward
+					target.Rewards[i] += baseReward * prevEpochTargetStake / totalBalance
+				} else {
+					//Boundary-attestation-non-participation R-penalty
+					target.Penalties[i] += baseReward
+				}
+
+				// Expected head
+				if status.Flags.HasMarkers(PrevHeadAttester | UnslashedAttester) {
+					// Canonical-participation reward
+					head.Rewards[i] += baseReward * prevEpochHeadStake / totalBalance
+				} else {
+					// Non-canonical-participation R-penalty
+					head.Penalties[i] += baseReward
+				}
+
+				// Take away max rewards if we're not finalizing
+				if finalityDelay > MIN_EPOCHS_TO_INACTIVITY_PENALTY {
+					inactivity.Penalties[i] += baseReward * BASE_REWARDS_PER_EPOCH
+					if !status.Flags.HasMarkers(PrevHeadAttester | UnslashedAttester) {
+						inactivity.Penalties[i] += effBalance * Gwei(finalityDelay) / INACTIVITY_PENALTY_QUOTIENT
+					}
 				}
 			}
 		}
+	})
+
+	// Proposer rewards for the inclusion of attestations.
+	// Done separately, as these change the deltas of validators outside of the range of a worker.
+	for i := ValidatorIndex(0); i < validatorCount; i++ {
+		status := attesterStatuses[i]
+		if status.Flags.HasMarkers(EligibleAttester | PrevSourceAttester | UnslashedAttester) {
+			proposerInclusion.Rewards[status.AttestedProposer] += baseReward(i) / PROPOSER_REWARD_QUOTIENT
+		}
+	}
+
+	if report != nil {
+		for _, d := range []*Deltas{source, target, head, inclusion, proposerInclusion, inactivity} {
+			deltas.Add(d)
+		}
+		report.Source.Add(source)
+		report.Target.Add(target)
+		report.Head.Add(head)
+		report.InclusionDelay.Add(inclusion)
+		report.ProposerInclusion.Add(proposerInclusion)
+		report.Inactivity.Add(inactivity)
 	}
 
 	return deltas
@@ -1,7 +1,6 @@
 package attestations
 
 import (
-	"errors"
 	"fmt"
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
@@ -35,25 +34,25 @@ func (indexedAttestation *IndexedAttestation) Validate(m AttestationValidator) e
 
 	// Verify max number of indices
 	if count := len(indices); count > MAX_VALIDATORS_PER_COMMITTEE {
-		return fmt.Errorf("invalid indices count in indexed attestation: %d", count)
+		return fmt.Errorf("%w: %d", IndexedAttestationInvalidCount, count)
 	}
 
 	// The indices must be sorted
 	if !sort.IsSorted(indices) {
-		return errors.New("attestation indices are not sorted")
+		return IndexedAttestationUnsorted
 	}
 
 	// Verify if the indices are unique. Simple O(n) check, since they are already sorted.
 	for i := 1; i < len(indices); i++ {
 		if indices[i-1] == indices[i] {
-			return fmt.Errorf("attestation indices at %d and %d are duplicate, both: %d", i-1, i, indices[i])
+			return fmt.Errorf("%w: at %d and %d, both: %d", IndexedAttestationDuplicateIndex, i-1, i, indices[i])
 		}
 	}
 
 	// Check the last item of the sorted list to be a valid index,
 	// if this one is valid, the others are as well, since they are lower.
 	if len(indices) > 0 && !m.IsValidIndex(indices[len(indices)-1]) {
-		return errors.New("attestation indices contains out of range index")
+		return IndexedAttestationIndexOutOfRange
 	}
 
 	pubkeys := make([]BLSPubkey, 0, 2)
@@ -73,7 +72,7 @@ func (indexedAttestation *IndexedAttestation) Validate(m AttestationValidator) e
 		indexedAttestation.Signature,
 		m.GetDomain(DOMAIN_BEACON_ATTESTER, indexedAttestation.Data.Target.Epoch),
 	) {
-		return errors.New("could not verify BLS signature for indexed attestation")
+		return IndexedAttestationInvalidSignature
 	}
 
 	return nil
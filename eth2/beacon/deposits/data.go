@@ -1,7 +1,11 @@
 package deposits
 
 import (
+	"fmt"
 	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
 	"github.com/protolambda/zssz"
 )
 
@@ -30,3 +34,48 @@ type DepositMessage struct {
 	WithdrawalCredentials Root
 	Amount                Gwei
 }
+
+// Withdrawal credentials of a BLS withdrawal key:
+// the hash of the withdrawal pubkey, with the first byte replaced by the BLS_WITHDRAWAL_PREFIX.
+func BlsWithdrawalCredentials(withdrawalPubkey BLSPubkey) Root {
+	creds := hashing.Hash(withdrawalPubkey[:])
+	creds[0] = BLS_WITHDRAWAL_PREFIX
+	return creds
+}
+
+// Deposits are valid across forks, the deposit domain is not based on the fork of the state.
+func DepositDomain() BLSDomain {
+	return ComputeDomain(DOMAIN_DEPOSIT, Version{})
+}
+
+// Creates deposit data for the validator of the given secret key,
+// signed as proof of possession, with BLS withdrawal credentials for the given withdrawal pubkey.
+func NewDepositData(secretKey [32]byte, withdrawalPubkey BLSPubkey, amount Gwei) *DepositData {
+	data := &DepositData{
+		Pubkey:                bls.BlsSecretToPubkey(secretKey),
+		WithdrawalCredentials: BlsWithdrawalCredentials(withdrawalPubkey),
+		Amount:                amount,
+	}
+	data.Signature = bls.BlsSign(secretKey, ssz.HashTreeRoot(data.Message(), DepositMessageSSZ), DepositDomain())
+	return data
+}
+
+// Checks the amount bounds, the withdrawal credentials prefix and the signature of the deposit data.
+// The beacon chain accepts deposits that fail this validation,
+// but the deposit is then lost (invalid signature) or may not activate a validator (insufficient amount).
+func (data *DepositData) Validate() error {
+	if data.Amount < MIN_DEPOSIT_AMOUNT {
+		return fmt.Errorf("deposit amount %d is less than the minimum deposit amount %d", data.Amount, MIN_DEPOSIT_AMOUNT)
+	}
+	if data.Amount > MAX_EFFECTIVE_BALANCE {
+		return fmt.Errorf("deposit amount %d is more than the max effective balance %d", data.Amount, MAX_EFFECTIVE_BALANCE)
+	}
+	if data.WithdrawalCredentials[0] != BLS_WITHDRAWAL_PREFIX {
+		return fmt.Errorf("withdrawal credentials prefix %d is not the BLS withdrawal prefix %d",
+			data.WithdrawalCredentials[0], BLS_WITHDRAWAL_PREFIX)
+	}
+	if !bls.BlsVerify(data.Pubkey, ssz.HashTreeRoot(data.Message(), DepositMessageSSZ), data.Signature, DepositDomain()) {
+		return fmt.Errorf("invalid deposit signature for pubkey %x", data.Pubkey)
+	}
+	return nil
+}
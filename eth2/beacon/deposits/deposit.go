@@ -1,7 +1,6 @@
 package deposits
 
 import (
-	"errors"
 	"fmt"
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
@@ -14,15 +13,18 @@ import (
 type DepositProcessor interface {
 	ProcessDeposits(ops []Deposit) error
 	ProcessDeposit(dep *Deposit) error
+	ValidateDeposit(dep *Deposit) error
 }
 
 type DepositFeature struct {
 	Meta interface {
 		meta.Pubkeys
+		meta.RegistrySize
 		meta.Deposits
 		meta.Balance
 		meta.Onboarding
 		meta.Depositing
+		meta.Observing
 	}
 }
 
@@ -34,12 +36,13 @@ func (f *DepositFeature) ProcessDeposits(ops []Deposit) error {
 		expectedCount = MAX_DEPOSITS
 	}
 	if depositCount != expectedCount {
-		return errors.New("block does not contain expected deposits amount")
+		return NewOperationError(DepositOperation, DepositCountMismatch,
+			fmt.Errorf("expected %d deposits, got %d", expectedCount, depositCount))
 	}
 
 	for i := range ops {
 		if err := f.ProcessDeposit(&ops[i]); err != nil {
-			return err
+			return WithOperationIndex(err, i)
 		}
 	}
 	return nil
@@ -52,8 +55,9 @@ type Deposit struct {
 	Data  DepositData
 }
 
-// Process an Eth1 deposit, registering a validator or increasing its balance.
-func (f *DepositFeature) ProcessDeposit(dep *Deposit) error {
+// Checks if the deposit is valid to process as the next deposit, without changing the state.
+// Deposits with an invalid signature are valid, but do not register a validator when processed.
+func (f *DepositFeature) ValidateDeposit(dep *Deposit) error {
 	depositIndex := f.Meta.DepIndex()
 
 	// Verify the Merkle branch
@@ -63,8 +67,18 @@ func (f *DepositFeature) ProcessDeposit(dep *Deposit) error {
 		DEPOSIT_CONTRACT_TREE_DEPTH+1, // Add 1 for the `List` length mix-in
 		uint64(depositIndex),
 		f.Meta.DepRoot()) {
-		return fmt.Errorf("deposit %d merkle proof failed to be verified", depositIndex)
+		return NewOperationError(DepositOperation, DepositInvalidProof,
+			fmt.Errorf("deposit index %d", depositIndex))
+	}
+	return nil
+}
+
+// Process an Eth1 deposit, registering a validator or increasing its balance.
+func (f *DepositFeature) ProcessDeposit(dep *Deposit) error {
+	if err := f.ValidateDeposit(dep); err != nil {
+		return err
 	}
+	depositIndex := f.Meta.DepIndex()
 
 	// Increment the next deposit index we are expecting. Note that this
 	// needs to be done here because while the deposit contract will never
@@ -79,12 +93,12 @@ func (f *DepositFeature) ProcessDeposit(dep *Deposit) error {
 		// Verify the deposit signature (proof of possession) for new validators.
 		// Only unknown pubkeys need to be verified, others are already trusted
 		// Note: The deposit contract does not check signatures.
-		// Note: Deposits are valid across forks, thus the deposit domain is retrieved directly from ComputeDomain().
+		// Note: Deposits are valid across forks, thus the deposit domain is not retrieved from the state.
 		if !bls.BlsVerify(
 			dep.Data.Pubkey,
 			ssz.HashTreeRoot(dep.Data.Message(), DepositMessageSSZ),
 			dep.Data.Signature,
-			ComputeDomain(DOMAIN_DEPOSIT, Version{})) {
+			DepositDomain()) {
 			// invalid signatures are OK,
 			// the depositor will not receive anything because of their mistake,
 			// and the chain continues.
@@ -93,10 +107,12 @@ func (f *DepositFeature) ProcessDeposit(dep *Deposit) error {
 
 		// Add validator and balance entries
 		f.Meta.AddNewValidator(dep.Data.Pubkey, dep.Data.WithdrawalCredentials, dep.Data.Amount)
-
+		// the new validator is appended to the registry, no need to search for it
+		valIndex = ValidatorIndex(f.Meta.ValidatorCount() - 1)
 	} else {
 		// Increase balance by deposit amount
 		f.Meta.IncreaseBalance(valIndex, dep.Data.Amount)
 	}
+	f.Meta.GetObserver().DepositApplied(depositIndex, valIndex, dep.Data.Amount, !exists)
 	return nil
 }
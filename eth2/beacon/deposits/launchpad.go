@@ -0,0 +1,138 @@
+package deposits
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"io"
+	"os"
+	"strings"
+)
+
+// Deposit data in the JSON format of the deposit launchpad, as created by the deposit CLI.
+// Bytes are encoded as hex, without 0x prefix.
+type LaunchpadDepositData struct {
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	Amount                uint64 `json:"amount"`
+	Signature             string `json:"signature"`
+	DepositMessageRoot    string `json:"deposit_message_root"`
+	DepositDataRoot       string `json:"deposit_data_root"`
+	ForkVersion           string `json:"fork_version"`
+}
+
+func (data *DepositData) Launchpad() *LaunchpadDepositData {
+	msgRoot := ssz.HashTreeRoot(data.Message(), DepositMessageSSZ)
+	dataRoot := ssz.HashTreeRoot(data, DepositDataSSZ)
+	var version Version
+	return &LaunchpadDepositData{
+		Pubkey:                hex.EncodeToString(data.Pubkey[:]),
+		WithdrawalCredentials: hex.EncodeToString(data.WithdrawalCredentials[:]),
+		Amount:                uint64(data.Amount),
+		Signature:             hex.EncodeToString(data.Signature[:]),
+		DepositMessageRoot:    hex.EncodeToString(msgRoot[:]),
+		DepositDataRoot:       hex.EncodeToString(dataRoot[:]),
+		ForkVersion:           hex.EncodeToString(version[:]),
+	}
+}
+
+func decodeHex(dst []byte, name string, v string) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", name, err)
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("invalid %s: expected %d bytes, got %d", name, len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
+
+// Decodes the deposit data, and checks the included roots and fork version.
+func (l *LaunchpadDepositData) DepositData() (*DepositData, error) {
+	data := &DepositData{Amount: Gwei(l.Amount)}
+	if err := decodeHex(data.Pubkey[:], "pubkey", l.Pubkey); err != nil {
+		return nil, err
+	}
+	if err := decodeHex(data.WithdrawalCredentials[:], "withdrawal credentials", l.WithdrawalCredentials); err != nil {
+		return nil, err
+	}
+	if err := decodeHex(data.Signature[:], "signature", l.Signature); err != nil {
+		return nil, err
+	}
+	var msgRoot, dataRoot Root
+	if err := decodeHex(msgRoot[:], "deposit message root", l.DepositMessageRoot); err != nil {
+		return nil, err
+	}
+	if err := decodeHex(dataRoot[:], "deposit data root", l.DepositDataRoot); err != nil {
+		return nil, err
+	}
+	var version Version
+	if err := decodeHex(version[:], "fork version", l.ForkVersion); err != nil {
+		return nil, err
+	}
+	if version != (Version{}) {
+		return nil, fmt.Errorf("deposits are signed with the genesis fork version %x, got %x", Version{}, version)
+	}
+	if root := ssz.HashTreeRoot(data.Message(), DepositMessageSSZ); root != msgRoot {
+		return nil, fmt.Errorf("deposit message root %x does not match deposit message %x", msgRoot, root)
+	}
+	if root := ssz.HashTreeRoot(data, DepositDataSSZ); root != dataRoot {
+		return nil, fmt.Errorf("deposit data root %x does not match deposit data %x", dataRoot, root)
+	}
+	return data, nil
+}
+
+// Writes the deposit data as a JSON list in the launchpad format.
+func WriteLaunchpadJSON(w io.Writer, deposits []*DepositData) error {
+	out := make([]*LaunchpadDepositData, 0, len(deposits))
+	for _, d := range deposits {
+		out = append(out, d.Launchpad())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// Reads a JSON list of deposit data in the launchpad format. The deposit data is decoded, but not validated.
+func ReadLaunchpadJSON(r io.Reader) ([]*DepositData, error) {
+	var in []*LaunchpadDepositData
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, err
+	}
+	out := make([]*DepositData, 0, len(in))
+	for i, l := range in {
+		d, err := l.DepositData()
+		if err != nil {
+			return nil, fmt.Errorf("deposit %d: %v", i, err)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Validates a batch of deposit data files in the launchpad format.
+// Returns an error for every invalid file or deposit, none if all deposits are valid.
+func ValidateLaunchpadFiles(paths ...string) (errs []error) {
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deposits, err := ReadLaunchpadJSON(f)
+		_ = f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", p, err))
+			continue
+		}
+		for i, d := range deposits {
+			if err := d.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: deposit %d: %v", p, i, err))
+			}
+		}
+	}
+	return errs
+}
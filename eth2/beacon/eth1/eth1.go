@@ -1,8 +1,8 @@
 package eth1
 
 import (
-	"errors"
 	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
 )
 
 type Eth1VoteProcessor interface {
@@ -55,7 +55,7 @@ func (state *Eth1State) ResetEth1Votes() {
 
 func (state *Eth1State) ProcessEth1Vote(data Eth1Data) error {
 	if Slot(len(state.Eth1DataVotes)) >= SLOTS_PER_ETH1_VOTING_PERIOD {
-		return errors.New("cannot process Eth1 vote, already voted maximum times")
+		return NewOperationError(Eth1VoteOperation, Eth1VotesExceeded, nil)
 	}
 	state.Eth1DataVotes = append(state.Eth1DataVotes, data)
 	// only do costly counting if we have enough votes yet.
@@ -72,3 +72,25 @@ func (state *Eth1State) ProcessEth1Vote(data Eth1Data) error {
 	}
 	return nil
 }
+
+// Processes eth1 votes like the eth1 state does, and emits an event when the eth1 data changes.
+// And chooses the eth1 votes for block proposals.
+type Eth1VotingFeature struct {
+	State *Eth1State
+	Meta  interface {
+		meta.Observing
+		meta.Genesis
+		meta.Versioning
+	}
+}
+
+func (f *Eth1VotingFeature) ProcessEth1Vote(data Eth1Data) error {
+	prev := f.State.Eth1Data
+	if err := f.State.ProcessEth1Vote(data); err != nil {
+		return err
+	}
+	if cur := f.State.Eth1Data; cur != prev {
+		f.Meta.GetObserver().Eth1DataUpdated(cur.DepositRoot, cur.DepositCount, cur.BlockHash)
+	}
+	return nil
+}
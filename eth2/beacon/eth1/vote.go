@@ -0,0 +1,113 @@
+package eth1
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"sort"
+)
+
+// An eth1 block, with the deposit contract data at the end of the block.
+type Eth1Block struct {
+	Hash         Root
+	Number       uint64
+	Timestamp    Timestamp
+	DepositRoot  Root
+	DepositCount DepositIndex
+}
+
+func (b *Eth1Block) Eth1Data() Eth1Data {
+	return Eth1Data{
+		DepositRoot:  b.DepositRoot,
+		DepositCount: b.DepositCount,
+		BlockHash:    b.Hash,
+	}
+}
+
+// Provides the eth1 blocks to choose an eth1 vote from.
+type Eth1BlockProvider interface {
+	// Returns the eth1 blocks with a timestamp in the given range (both inclusive), ordered by block number.
+	BlocksByTime(min Timestamp, max Timestamp) ([]*Eth1Block, error)
+}
+
+// Start time of the eth1 voting period the given slot is part of.
+func VotingPeriodStartTime(genesisTime Timestamp, slot Slot) Timestamp {
+	return (slot - slot%SLOTS_PER_ETH1_VOTING_PERIOD).ToTimestamp(genesisTime)
+}
+
+// Eth1 blocks are candidates for voting if they are at least ETH1_FOLLOW_DISTANCE blocks (approximated by time)
+// old at the start of the voting period, but not more than twice that.
+func IsCandidateBlock(block *Eth1Block, periodStart Timestamp) bool {
+	followTime := SECONDS_PER_ETH1_BLOCK * Timestamp(ETH1_FOLLOW_DISTANCE)
+	return block.Timestamp+followTime <= periodStart && block.Timestamp+followTime*2 >= periodStart
+}
+
+// Chooses the eth1 vote for a block proposal at the current slot of the state, following the honest validator spec.
+// Valid votes already in the state are followed by majority, with the earliest vote winning a tie.
+// Without valid votes, the most recent candidate block is voted for, or the current eth1 data if there are none.
+func (f *Eth1VotingFeature) GetEth1Vote(provider Eth1BlockProvider) (Eth1Data, error) {
+	periodStart := VotingPeriodStartTime(f.Meta.GetGenesisTime(), f.Meta.CurrentSlot())
+	followTime := SECONDS_PER_ETH1_BLOCK * Timestamp(ETH1_FOLLOW_DISTANCE)
+	if periodStart < followTime {
+		return f.State.Eth1Data, nil
+	}
+	min := Timestamp(0)
+	if periodStart > followTime*2 {
+		min = periodStart - followTime*2
+	}
+	blocks, err := provider.BlocksByTime(min, periodStart-followTime)
+	if err != nil {
+		return Eth1Data{}, err
+	}
+	candidates := make(map[Eth1Data]struct{}, len(blocks))
+	var defaultVote *Eth1Data
+	for _, b := range blocks {
+		// deposit counts may not decrease, the provider may return blocks outside the window.
+		if !IsCandidateBlock(b, periodStart) || b.DepositCount < f.State.Eth1Data.DepositCount {
+			continue
+		}
+		data := b.Eth1Data()
+		candidates[data] = struct{}{}
+		defaultVote = &data
+	}
+	if defaultVote == nil {
+		return f.State.Eth1Data, nil
+	}
+
+	counts := make(map[Eth1Data]uint64)
+	for _, vote := range f.State.Eth1DataVotes {
+		if _, ok := candidates[vote]; ok {
+			counts[vote] += 1
+		}
+	}
+	best, bestCount := *defaultVote, uint64(0)
+	for _, vote := range f.State.Eth1DataVotes {
+		// strictly more votes, the earliest vote wins ties.
+		if c := counts[vote]; c > bestCount {
+			best, bestCount = vote, c
+		}
+	}
+	return best, nil
+}
+
+// An in-memory eth1 chain, e.g. for testing.
+type MemoryEth1Chain struct {
+	// Blocks, ordered by block number (and thus timestamp).
+	Blocks []*Eth1Block
+}
+
+// Adds the block to the chain. Blocks must be added in order.
+func (c *MemoryEth1Chain) AddBlock(block *Eth1Block) {
+	c.Blocks = append(c.Blocks, block)
+}
+
+func (c *MemoryEth1Chain) BlocksByTime(min Timestamp, max Timestamp) ([]*Eth1Block, error) {
+	start := sort.Search(len(c.Blocks), func(i int) bool {
+		return c.Blocks[i].Timestamp >= min
+	})
+	end := sort.Search(len(c.Blocks), func(i int) bool {
+		return c.Blocks[i].Timestamp > max
+	})
+	if end < start {
+		end = start
+	}
+	return c.Blocks[start:end], nil
+}
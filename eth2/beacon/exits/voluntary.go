@@ -1,7 +1,6 @@
 package exits
 
 import (
-	"errors"
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
 	"github.com/protolambda/zrnt/eth2/util/bls"
@@ -12,6 +11,7 @@ import (
 type VoluntaryExitProcessor interface {
 	ProcessVoluntaryExits(ops []SignedVoluntaryExit) error
 	ProcessVoluntaryExit(signedExit *SignedVoluntaryExit) error
+	ValidateVoluntaryExit(signedExit *SignedVoluntaryExit) error
 }
 
 type VoluntaryExitFeature struct {
@@ -26,7 +26,7 @@ type VoluntaryExitFeature struct {
 func (f *VoluntaryExitFeature) ProcessVoluntaryExits(ops []SignedVoluntaryExit) error {
 	for i := range ops {
 		if err := f.ProcessVoluntaryExit(&ops[i]); err != nil {
-			return err
+			return WithOperationIndex(err, i)
 		}
 	}
 	return nil
@@ -46,28 +46,29 @@ type SignedVoluntaryExit struct {
 	Signature      BLSSignature
 }
 
-func (f *VoluntaryExitFeature) ProcessVoluntaryExit(signedExit *SignedVoluntaryExit) error {
+// Checks if the voluntary exit is valid to include in a block on top of the current state, without changing the state.
+func (f *VoluntaryExitFeature) ValidateVoluntaryExit(signedExit *SignedVoluntaryExit) error {
 	exit := &signedExit.Message
 	currentEpoch := f.Meta.CurrentEpoch()
 	if !f.Meta.IsValidIndex(exit.ValidatorIndex) {
-		return errors.New("invalid exit validator index")
+		return NewOperationError(VoluntaryExitOperation, ExitInvalidIndex, nil)
 	}
 	validator := f.Meta.Validator(exit.ValidatorIndex)
 	// Verify that the validator is active
 	if !validator.IsActive(currentEpoch) {
-		return errors.New("validator must be active to be able to voluntarily exit")
+		return NewOperationError(VoluntaryExitOperation, ExitValidatorInactive, nil)
 	}
 	// Verify the validator has not yet exited
 	if validator.ExitEpoch != FAR_FUTURE_EPOCH {
-		return errors.New("validator already exited")
+		return NewOperationError(VoluntaryExitOperation, ExitAlreadyExited, nil)
 	}
 	// Exits must specify an epoch when they become valid; they are not valid before then
 	if currentEpoch < exit.Epoch {
-		return errors.New("invalid exit epoch")
+		return NewOperationError(VoluntaryExitOperation, ExitEpochInFuture, nil)
 	}
 	// Verify the validator has been active long enough
 	if currentEpoch < validator.ActivationEpoch+PERSISTENT_COMMITTEE_PERIOD {
-		return errors.New("exit is too soon")
+		return NewOperationError(VoluntaryExitOperation, ExitTooSoon, nil)
 	}
 	// Verify signature
 	if !bls.BlsVerify(
@@ -75,9 +76,16 @@ func (f *VoluntaryExitFeature) ProcessVoluntaryExit(signedExit *SignedVoluntaryE
 		ssz.HashTreeRoot(exit, VoluntaryExitSSZ),
 		signedExit.Signature,
 		f.Meta.GetDomain(DOMAIN_VOLUNTARY_EXIT, exit.Epoch)) {
-		return errors.New("voluntary exit signature could not be verified")
+		return NewOperationError(VoluntaryExitOperation, ExitInvalidSignature, nil)
+	}
+	return nil
+}
+
+func (f *VoluntaryExitFeature) ProcessVoluntaryExit(signedExit *SignedVoluntaryExit) error {
+	if err := f.ValidateVoluntaryExit(signedExit); err != nil {
+		return err
 	}
 	// Initiate exit
-	f.Meta.InitiateValidatorExit(f.Meta.CurrentEpoch(), exit.ValidatorIndex)
+	f.Meta.InitiateValidatorExit(f.Meta.CurrentEpoch(), signedExit.Message.ValidatorIndex)
 	return nil
 }
@@ -18,6 +18,7 @@ type FinalUpdateFeature struct {
 		meta.Randao
 		meta.HistoryUpdate
 		meta.EpochAttestations
+		meta.Concurrency
 	}
 }
 
@@ -33,7 +34,7 @@ func (f *FinalUpdateFeature) ProcessEpochFinalUpdates() {
 		f.Meta.ResetEth1Votes()
 	}
 
-	f.Meta.UpdateEffectiveBalances()
+	f.Meta.UpdateEffectiveBalances(f.Meta.GetConcurrency())
 	f.Meta.ResetSlashings(nextEpoch)
 	f.Meta.PrepareRandao(nextEpoch)
 
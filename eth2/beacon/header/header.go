@@ -1,7 +1,6 @@
 package header
 
 import (
-	"errors"
 	"fmt"
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
@@ -44,18 +43,19 @@ func (f *BlockHeaderFeature) ProcessHeader(header *BeaconBlockHeader) error {
 	currentSlot := f.Meta.CurrentSlot()
 	// Verify that the slots match
 	if header.Slot != currentSlot {
-		return errors.New("slot of block does not match slot of state")
+		return NewOperationError(HeaderOperation, HeaderSlotMismatch, nil)
 	}
 	// Verify that the parent matches
 	if latestRoot := f.Meta.GetLatestBlockRoot(); header.ParentRoot != latestRoot {
-		return fmt.Errorf("previous block root %x does not match root %x from latest state block header", header.ParentRoot, latestRoot)
+		return NewOperationError(HeaderOperation, HeaderParentRootMismatch,
+			fmt.Errorf("previous block root %x does not match root %x", header.ParentRoot, latestRoot))
 	}
 
 	proposerIndex := f.Meta.GetBeaconProposerIndex(currentSlot)
 
 	// Verify proposer is not slashed
 	if f.Meta.IsSlashed(proposerIndex) {
-		return errors.New("cannot accept block header from slashed proposer")
+		return NewOperationError(HeaderOperation, HeaderProposerSlashed, nil)
 	}
 
 	// Store as the new latest block
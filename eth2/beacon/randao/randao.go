@@ -1,7 +1,6 @@
 package randao
 
 import (
-	"errors"
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
 	"github.com/protolambda/zrnt/eth2/util/bls"
@@ -58,7 +57,7 @@ func (f *RandaoFeature) ProcessRandaoReveal(reveal BLSSignature) error {
 		reveal,
 		f.Meta.GetDomain(DOMAIN_RANDAO, epoch),
 	) {
-		return errors.New("randao invalid")
+		return NewOperationError(RandaoOperation, RandaoInvalidReveal, nil)
 	}
 	// Mix in RANDAO reveal
 	mix := XorBytes32(f.State.GetRandomMix(epoch), Hash(reveal[:]))
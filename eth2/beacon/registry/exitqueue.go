@@ -0,0 +1,64 @@
+package registry
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/math"
+)
+
+// Pre-computed exit queue data of an epoch: the active validator count, and a histogram of the exit epochs.
+// Computes the churn limit and exit queue end in O(1), instead of scanning the registry for every exit.
+// Valid as long as every initiated exit is tracked with TrackExit. The churn limit of other epochs is computed
+// from the registry the data was loaded from.
+type ExitQueueData struct {
+	Epoch       Epoch
+	ActiveCount uint64
+	// The amount of validators per exit epoch, of all validators that initiated an exit
+	ExitEpochs map[Epoch]uint64
+	// The highest exit epoch of all validators that initiated an exit, zero if none did
+	MaxExitEpoch Epoch
+
+	state *ValidatorsState
+}
+
+func (state *ValidatorsState) LoadExitQueueData(epoch Epoch) *ExitQueueData {
+	data := &ExitQueueData{
+		Epoch:      epoch,
+		ExitEpochs: make(map[Epoch]uint64),
+		state:      state,
+	}
+	for _, v := range state.Validators {
+		if v.IsActive(epoch) {
+			data.ActiveCount++
+		}
+		if v.ExitEpoch != FAR_FUTURE_EPOCH {
+			data.TrackExit(v.ExitEpoch)
+		}
+	}
+	return data
+}
+
+// Tracks the exit epoch of a validator that initiated an exit.
+func (data *ExitQueueData) TrackExit(exitEpoch Epoch) {
+	data.ExitEpochs[exitEpoch]++
+	if exitEpoch > data.MaxExitEpoch {
+		data.MaxExitEpoch = exitEpoch
+	}
+}
+
+func (data *ExitQueueData) GetChurnLimit(epoch Epoch) uint64 {
+	if epoch != data.Epoch {
+		return data.state.GetChurnLimit(epoch)
+	}
+	return math.MaxU64(MIN_PER_EPOCH_CHURN_LIMIT, data.ActiveCount/CHURN_LIMIT_QUOTIENT)
+}
+
+func (data *ExitQueueData) ExitQueueEnd(epoch Epoch) Epoch {
+	exitQueueEnd := epoch.ComputeActivationExitEpoch()
+	if data.MaxExitEpoch > exitQueueEnd {
+		exitQueueEnd = data.MaxExitEpoch
+	}
+	if data.ExitEpochs[exitQueueEnd] >= data.GetChurnLimit(epoch) {
+		exitQueueEnd++
+	}
+	return exitQueueEnd
+}
@@ -0,0 +1,157 @@
+package registry
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"sort"
+)
+
+// A validator waiting for activation, with the activation eligibility epoch it is ordered by in the activation queue.
+type PendingActivation struct {
+	Index            ValidatorIndex
+	EligibilityEpoch Epoch
+}
+
+// The validators waiting for activation, ordered like ProcessActivationQueue: by eligibility epoch, then index.
+// Validators that are not in the queue yet, but are eligible to join it at the end of the current epoch,
+// are included with the eligibility epoch they will get.
+func (state *ValidatorsState) PendingActivations(currentEpoch Epoch) (out []PendingActivation) {
+	for i, v := range state.Validators {
+		if v.ActivationEpoch != FAR_FUTURE_EPOCH {
+			continue
+		}
+		if v.ActivationEligibilityEpoch != FAR_FUTURE_EPOCH {
+			out = append(out, PendingActivation{Index: ValidatorIndex(i), EligibilityEpoch: v.ActivationEligibilityEpoch})
+		} else if v.IsEligibleForActivationQueue() {
+			out = append(out, PendingActivation{Index: ValidatorIndex(i), EligibilityEpoch: currentEpoch + 1})
+		}
+	}
+	sort.Slice(out, func(i int, j int) bool {
+		if out[i].EligibilityEpoch == out[j].EligibilityEpoch {
+			return out[i].Index < out[j].Index
+		}
+		return out[i].EligibilityEpoch < out[j].EligibilityEpoch
+	})
+	return out
+}
+
+// The position of the validator in the activation queue, zero for the front of the queue.
+// False if the validator is not waiting for activation.
+func (state *ValidatorsState) ActivationQueuePosition(index ValidatorIndex, currentEpoch Epoch) (position uint64, ok bool) {
+	for i, p := range state.PendingActivations(currentEpoch) {
+		if p.Index == index {
+			return uint64(i), true
+		}
+	}
+	return 0, false
+}
+
+// Estimates the activation epoch of the validator, based on its position in the activation queue and the churn limit.
+// Assumes that finality keeps up after the current epoch, and that the churn limit does not change.
+// Returns FAR_FUTURE_EPOCH if the validator is not waiting for activation, e.g. because of an insufficient balance.
+func (state *ValidatorsState) EstimateActivationEpoch(index ValidatorIndex, currentEpoch Epoch, finalizedEpoch Epoch) Epoch {
+	if v := state.Validators[index]; v.ActivationEpoch != FAR_FUTURE_EPOCH {
+		return v.ActivationEpoch
+	}
+	queue := state.PendingActivations(currentEpoch)
+	position := -1
+	for i, p := range queue {
+		if p.Index == index {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return FAR_FUTURE_EPOCH
+	}
+	churnLimit := state.GetChurnLimit(currentEpoch)
+	dequeued := 0
+	for epoch := currentEpoch; ; epoch++ {
+		// with finality keeping up, the previous epoch is finalized when processing the epoch.
+		if epoch > currentEpoch && epoch-1 > finalizedEpoch {
+			finalizedEpoch = epoch - 1
+		}
+		// validators eligible for activation are always at the front of the queue.
+		for n := uint64(0); n < churnLimit && dequeued < len(queue) && queue[dequeued].EligibilityEpoch <= finalizedEpoch; n++ {
+			dequeued++
+		}
+		if dequeued > position {
+			return epoch.ComputeActivationExitEpoch()
+		}
+	}
+}
+
+type ProjectedExit struct {
+	ExitEpoch         Epoch
+	WithdrawableEpoch Epoch
+}
+
+// Projection of the activation and exit queues.
+type QueueProjection struct {
+	// The projected activation epoch of every validator waiting for activation.
+	Activations map[ValidatorIndex]Epoch
+	// The projected exit of every validator that is exiting, or will be ejected or exit.
+	Exits map[ValidatorIndex]ProjectedExit
+}
+
+// Projects the activation and exit queues forward, without changing the state.
+// The given exits are initiated first, in order, as if included in a block in the current epoch.
+// Then the registry updates are projected for every next epoch, like ProcessEpochRegistryUpdates,
+// until all pending validators are activated. Assumes that finality keeps up after the current epoch,
+// and that effective balances do not change.
+func (state *ValidatorsState) ProjectQueues(currentEpoch Epoch, finalizedEpoch Epoch, exits []ValidatorIndex) *QueueProjection {
+	// work on a copy of the validators, to reuse the registry logic without changing the state
+	projected := &RegistryState{ValidatorsState: ValidatorsState{Validators: make(ValidatorRegistry, len(state.Validators))}}
+	for i, v := range state.Validators {
+		c := *v
+		projected.Validators[i] = &c
+	}
+	queue := projected.LoadExitQueueData(currentEpoch)
+	for _, index := range exits {
+		projected.initiateValidatorExit(queue, currentEpoch, index)
+	}
+	out := &QueueProjection{
+		Activations: make(map[ValidatorIndex]Epoch),
+		Exits:       make(map[ValidatorIndex]ProjectedExit),
+	}
+	// validators that were dequeued already, but are not active yet
+	for i, v := range projected.Validators {
+		if v.ActivationEpoch != FAR_FUTURE_EPOCH && v.ActivationEpoch > currentEpoch {
+			out.Activations[ValidatorIndex(i)] = v.ActivationEpoch
+		}
+	}
+	for epoch := currentEpoch; ; epoch++ {
+		if epoch > currentEpoch && epoch-1 > finalizedEpoch {
+			finalizedEpoch = epoch - 1
+		}
+		if queue.Epoch != epoch {
+			queue = projected.LoadExitQueueData(epoch)
+		}
+		for i, v := range projected.Validators {
+			if v.IsEligibleForActivationQueue() {
+				v.ActivationEligibilityEpoch = epoch + 1
+			}
+			if v.IsActive(epoch) && v.EffectiveBalance <= EJECTION_BALANCE {
+				projected.initiateValidatorExit(queue, epoch, ValidatorIndex(i))
+			}
+		}
+		for _, index := range projected.DequeueActivations(epoch, finalizedEpoch, queue.GetChurnLimit(epoch)) {
+			out.Activations[index] = projected.Validators[index].ActivationEpoch
+		}
+		pending := false
+		for _, v := range projected.Validators {
+			if v.ActivationEpoch == FAR_FUTURE_EPOCH && v.ActivationEligibilityEpoch != FAR_FUTURE_EPOCH {
+				pending = true
+				break
+			}
+		}
+		if !pending {
+			break
+		}
+	}
+	for i, v := range projected.Validators {
+		if v.ExitEpoch != FAR_FUTURE_EPOCH && v.ExitEpoch > currentEpoch {
+			out.Exits[ValidatorIndex(i)] = ProjectedExit{ExitEpoch: v.ExitEpoch, WithdrawableEpoch: v.WithdrawableEpoch}
+		}
+	}
+	return out
+}
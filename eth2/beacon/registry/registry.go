@@ -4,6 +4,7 @@ import (
 	. "github.com/protolambda/zrnt/eth2/beacon/validator"
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
+	"github.com/protolambda/zrnt/eth2/util/parallel"
 )
 
 // Validator registry
@@ -12,31 +13,56 @@ type RegistryState struct {
 	BalancesState
 }
 
-// Update effective balances with hysteresis
-func (state *RegistryState) UpdateEffectiveBalances() {
-	for i, v := range state.Validators {
-		balance := state.Balances[i]
-		if balance < v.EffectiveBalance ||
-			v.EffectiveBalance+3*HALF_INCREMENT < balance {
-			v.EffectiveBalance = balance - (balance % EFFECTIVE_BALANCE_INCREMENT)
-			if MAX_EFFECTIVE_BALANCE < v.EffectiveBalance {
-				v.EffectiveBalance = MAX_EFFECTIVE_BALANCE
+// Update effective balances with hysteresis, split over the given amount of workers.
+func (state *RegistryState) UpdateEffectiveBalances(workers int) {
+	parallel.Range(workers, uint64(len(state.Validators)), func(start uint64, end uint64) {
+		for i := start; i < end; i++ {
+			v := state.Validators[i]
+			balance := state.Balances[i]
+			if balance < v.EffectiveBalance ||
+				v.EffectiveBalance+3*HALF_INCREMENT < balance {
+				v.EffectiveBalance = balance - (balance % EFFECTIVE_BALANCE_INCREMENT)
+				if MAX_EFFECTIVE_BALANCE < v.EffectiveBalance {
+					v.EffectiveBalance = MAX_EFFECTIVE_BALANCE
+				}
 			}
 		}
-	}
+	})
 }
 
-// Initiate the exit of the validator of the given index
-func (state *RegistryState) InitiateValidatorExit(currentEpoch Epoch, index ValidatorIndex) {
+// The exit queue that validator exits are initiated in, e.g. the cached ExitQueueData.
+type ExitQueue interface {
+	ExitQueueEnd(epoch Epoch) Epoch
+	TrackExit(exitEpoch Epoch)
+}
+
+// Initiate the exit of the validator of the given index, and track it in the exit queue.
+// Returns false if the validator already initiated an exit.
+func (state *RegistryState) initiateValidatorExit(queue ExitQueue, currentEpoch Epoch, index ValidatorIndex) bool {
 	validator := state.Validators[index]
 	// Return if validator already initiated exit
 	if validator.ExitEpoch != FAR_FUTURE_EPOCH {
-		return
+		return false
 	}
 
 	// Set validator exit epoch and withdrawable epoch
-	validator.ExitEpoch = state.ExitQueueEnd(currentEpoch)
+	validator.ExitEpoch = queue.ExitQueueEnd(currentEpoch)
 	validator.WithdrawableEpoch = validator.ExitEpoch + MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	queue.TrackExit(validator.ExitEpoch)
+	return true
+}
+
+// Exit queue that scans the registry for the exit queue end, nothing is cached.
+type scanExitQueue struct {
+	*ValidatorsState
+}
+
+func (scanExitQueue) TrackExit(exitEpoch Epoch) {}
+
+// Initiate the exit of the validator of the given index, scanning the registry for the exit queue end.
+// Full featured states initiate exits through the ExitFeature instead, with the cached exit queue.
+func (state *RegistryState) InitiateValidatorExit(currentEpoch Epoch, index ValidatorIndex) {
+	state.initiateValidatorExit(scanExitQueue{&state.ValidatorsState}, currentEpoch, index)
 }
 
 func (state *RegistryState) AddNewValidator(pubkey BLSPubkey, withdrawalCreds Root, balance Gwei) {
@@ -67,6 +93,8 @@ type RegistryUpdatesFeature struct {
 		meta.Versioning
 		meta.Finality
 		meta.ActivationExit
+		meta.Exits
+		meta.Observing
 	}
 }
 
@@ -79,8 +107,28 @@ func (f *RegistryUpdatesFeature) ProcessEpochRegistryUpdates() {
 		}
 		if v.IsActive(currentEpoch) &&
 			v.EffectiveBalance <= EJECTION_BALANCE {
-			f.State.InitiateValidatorExit(currentEpoch, ValidatorIndex(i))
+			f.Meta.InitiateValidatorExit(currentEpoch, ValidatorIndex(i))
 		}
 	}
-	f.State.ProcessActivationQueue(currentEpoch, f.Meta.Finalized().Epoch)
+	for _, vi := range f.State.DequeueActivations(currentEpoch, f.Meta.Finalized().Epoch, f.Meta.GetChurnLimit(currentEpoch)) {
+		f.Meta.GetObserver().ValidatorActivated(vi, f.State.Validators[vi].ActivationEpoch)
+	}
+}
+
+// Initiates validator exits in the (cached) exit queue of the meta, and emits the exit events.
+// All exits are initiated through this feature, to keep the exit queue up to date.
+type ExitFeature struct {
+	State *RegistryState
+	Meta  interface {
+		meta.ActivationExit
+		meta.ExitTracking
+		meta.Observing
+	}
+}
+
+// Initiate the exit of the validator of the given index
+func (f *ExitFeature) InitiateValidatorExit(currentEpoch Epoch, index ValidatorIndex) {
+	if f.State.initiateValidatorExit(f.Meta, currentEpoch, index) {
+		f.Meta.GetObserver().ExitInitiated(index, f.State.Validators[index].ExitEpoch)
+	}
 }
@@ -143,7 +143,13 @@ func (state *ValidatorsState) ExitQueueEnd(epoch Epoch) Epoch {
 	return exitQueueEnd
 }
 
-func (state *ValidatorsState) ProcessActivationQueue(currentEpoch Epoch, finalizedEpoch Epoch) {
+// Dequeues validators for activation, and returns the indices of the validators that were dequeued.
+func (state *ValidatorsState) ProcessActivationQueue(currentEpoch Epoch, finalizedEpoch Epoch) (activated []ValidatorIndex) {
+	return state.DequeueActivations(currentEpoch, finalizedEpoch, state.GetChurnLimit(currentEpoch))
+}
+
+// Like ProcessActivationQueue, with the churn limit of the current epoch known already, e.g. from ExitQueueData.
+func (state *ValidatorsState) DequeueActivations(currentEpoch Epoch, finalizedEpoch Epoch, churnLimit uint64) (activated []ValidatorIndex) {
 	// Queue validators eligible for activation and not dequeued for activation prior to finalized epoch
 	activationQueue := make([]ValidatorIndex, 0)
 	for i, v := range state.Validators {
@@ -163,12 +169,14 @@ func (state *ValidatorsState) ProcessActivationQueue(currentEpoch Epoch, finaliz
 	})
 	// Dequeued validators for activation up to churn limit (without resetting activation epoch)
 	queueLen := uint64(len(activationQueue))
-	if churnLimit := state.GetChurnLimit(currentEpoch); churnLimit < queueLen {
+	if churnLimit < queueLen {
 		queueLen = churnLimit
 	}
-	for _, vi := range activationQueue[:queueLen] {
+	activated = activationQueue[:queueLen]
+	for _, vi := range activated {
 		state.Validators[vi].ActivationEpoch = currentEpoch.ComputeActivationExitEpoch()
 	}
+	return activated
 }
 
 // Return the total balance sum (1 Gwei minimum to avoid divisions by zero.)
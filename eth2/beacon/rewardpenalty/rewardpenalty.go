@@ -13,8 +13,10 @@ type RewardsAndPenaltiesFeature struct {
 	Meta interface {
 		meta.Versioning
 		meta.RegistrySize
+		meta.Balance
 		meta.BalanceDeltas
 		meta.AttestationDeltas
+		meta.RewardsReporting
 	}
 }
 
@@ -24,5 +26,14 @@ func (f *RewardsAndPenaltiesFeature) ProcessEpochRewardsAndPenalties() {
 	}
 	sum := NewDeltas(f.Meta.ValidatorCount())
 	sum.Add(f.Meta.AttestationDeltas())
+	if report := f.Meta.RewardsReport(); report != nil {
+		// Penalties are clipped to the balance, the report only keeps the penalties that are applied.
+		for i, penalty := range sum.Penalties {
+			index := ValidatorIndex(i)
+			if available := f.Meta.GetBalance(index) + sum.Rewards[i]; available < penalty {
+				report.ReduceAttestationPenalties(index, penalty-available)
+			}
+		}
+	}
 	f.Meta.ApplyDeltas(sum)
 }
@@ -1,7 +1,7 @@
 package attslash
 
 import (
-	"errors"
+	"fmt"
 	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
 	. "github.com/protolambda/zrnt/eth2/core"
 	"github.com/protolambda/zrnt/eth2/meta"
@@ -11,6 +11,7 @@ import (
 type AttesterSlashingProcessor interface {
 	ProcessAttesterSlashings(ops []AttesterSlashing) error
 	ProcessAttesterSlashing(attesterSlashing *AttesterSlashing) error
+	ValidateAttesterSlashing(attesterSlashing *AttesterSlashing) error
 }
 
 type AttestSlashFeature struct {
@@ -29,7 +30,7 @@ type AttestSlashFeature struct {
 func (f *AttestSlashFeature) ProcessAttesterSlashings(ops []AttesterSlashing) error {
 	for i := range ops {
 		if err := f.ProcessAttesterSlashing(&ops[i]); err != nil {
-			return err
+			return WithOperationIndex(err, i)
 		}
 	}
 	return nil
@@ -42,37 +43,55 @@ type AttesterSlashing struct {
 	Attestation2 IndexedAttestation
 }
 
-func (f *AttestSlashFeature) ProcessAttesterSlashing(attesterSlashing *AttesterSlashing) error {
+// Checks if the attester slashing is valid and slashes at least one validator, without changing the state.
+func (f *AttestSlashFeature) ValidateAttesterSlashing(attesterSlashing *AttesterSlashing) error {
 	sa1 := &attesterSlashing.Attestation1
 	sa2 := &attesterSlashing.Attestation2
 
 	if !IsSlashableAttestationData(&sa1.Data, &sa2.Data) {
-		return errors.New("attester slashing has no valid reasoning")
+		return NewOperationError(AttesterSlashingOperation, AttesterSlashingNotSlashable, nil)
 	}
 
 	if err := sa1.Validate(f.Meta); err != nil {
-		return errors.New("attestation 1 of attester slashing cannot be verified")
+		return NewOperationError(AttesterSlashingOperation, AttesterSlashingInvalidAttestation,
+			fmt.Errorf("attestation 1: %w", err))
 	}
 	if err := sa2.Validate(f.Meta); err != nil {
-		return errors.New("attestation 2 of attester slashing cannot be verified")
+		return NewOperationError(AttesterSlashingOperation, AttesterSlashingInvalidAttestation,
+			fmt.Errorf("attestation 2: %w", err))
+	}
+
+	currentEpoch := f.Meta.CurrentEpoch()
+
+	// check effectiveness: at least one validator in the intersection must be slashable
+	slashableAny := false
+	ValidatorSet(sa1.AttestingIndices).ZigZagJoin(ValidatorSet(sa2.AttestingIndices), func(i ValidatorIndex) {
+		if f.Meta.Validator(i).IsSlashable(currentEpoch) {
+			slashableAny = true
+		}
+	}, nil)
+	if !slashableAny {
+		return NewOperationError(AttesterSlashingOperation, AttesterSlashingNoEffect, nil)
 	}
+	return nil
+}
 
-	// keep track of effectiveness
-	slashedAny := false
+func (f *AttestSlashFeature) ProcessAttesterSlashing(attesterSlashing *AttesterSlashing) error {
+	if err := f.ValidateAttesterSlashing(attesterSlashing); err != nil {
+		return err
+	}
+	sa1 := &attesterSlashing.Attestation1
+	sa2 := &attesterSlashing.Attestation2
 
 	currentEpoch := f.Meta.CurrentEpoch()
 
-	// run slashings where applicable
+	// run slashings where applicable (validated above to be effective)
 	// use ZigZagJoin for efficient intersection: the indicies are already sorted (as validated above)
 	ValidatorSet(sa1.AttestingIndices).ZigZagJoin(ValidatorSet(sa2.AttestingIndices), func(i ValidatorIndex) {
 		if f.Meta.Validator(i).IsSlashable(currentEpoch) {
 			f.Meta.SlashValidator(i, nil)
-			slashedAny = true
 		}
 	}, nil)
-	if !slashedAny {
-		return errors.New("attester slashing %d is not effective, hence invalid")
-	}
 	return nil
 }
 
@@ -13,6 +13,7 @@ import (
 type ProposerSlashingProcessor interface {
 	ProcessProposerSlashings(ops []ProposerSlashing) error
 	ProcessProposerSlashing(ps *ProposerSlashing) error
+	ValidateProposerSlashing(ps *ProposerSlashing) error
 }
 
 type PropSlashFeature struct {
@@ -38,40 +39,50 @@ type ProposerSlashing struct {
 func (f *PropSlashFeature) ProcessProposerSlashings(ops []ProposerSlashing) error {
 	for i := range ops {
 		if err := f.ProcessProposerSlashing(&ops[i]); err != nil {
-			return err
+			return WithOperationIndex(err, i)
 		}
 	}
 	return nil
 }
 
-func (f *PropSlashFeature) ProcessProposerSlashing(ps *ProposerSlashing) error {
+// Checks if the proposer slashing is valid to include in a block on top of the current state, without changing the state.
+func (f *PropSlashFeature) ValidateProposerSlashing(ps *ProposerSlashing) error {
 	if !f.Meta.IsValidIndex(ps.ProposerIndex) {
-		return errors.New("invalid proposer index")
+		return NewOperationError(ProposerSlashingOperation, ProposerSlashingInvalidIndex, nil)
 	}
 	// Verify slots match
 	if ps.SignedHeader1.Message.Slot != ps.SignedHeader2.Message.Slot {
-		return errors.New("proposer slashing requires slashing headers to have the same slot")
+		return NewOperationError(ProposerSlashingOperation, ProposerSlashingSlotMismatch, nil)
 	}
 	// But the headers are different
 	if ps.SignedHeader1.Message == ps.SignedHeader2.Message {
-		return errors.New("proposer slashing requires two different headers")
+		return NewOperationError(ProposerSlashingOperation, ProposerSlashingSameHeaders, nil)
 	}
 	proposer := f.Meta.Validator(ps.ProposerIndex)
 	// Check proposer is slashable
 	if !proposer.IsSlashable(f.Meta.CurrentEpoch()) {
-		return errors.New("proposer slashing requires proposer to be slashable")
+		return NewOperationError(ProposerSlashingOperation, ProposerSlashingNotSlashable, nil)
 	}
 	// Signatures are valid
 	if !bls.BlsVerify(proposer.Pubkey, ssz.HashTreeRoot(ps.SignedHeader1.Message, BeaconBlockHeaderSSZ),
 		ps.SignedHeader1.Signature,
 		f.Meta.GetDomain(DOMAIN_BEACON_PROPOSER, ps.SignedHeader1.Message.Slot.ToEpoch())) {
-		return errors.New("proposer slashing header 1 has invalid BLS signature")
+		return NewOperationError(ProposerSlashingOperation, ProposerSlashingInvalidSignature,
+			errors.New("header 1"))
 	}
 	if !bls.BlsVerify(proposer.Pubkey,
 		ssz.HashTreeRoot(ps.SignedHeader2.Message, BeaconBlockHeaderSSZ),
 		ps.SignedHeader2.Signature,
 		f.Meta.GetDomain(DOMAIN_BEACON_PROPOSER, ps.SignedHeader2.Message.Slot.ToEpoch())) {
-		return errors.New("proposer slashing header 2 has invalid BLS signature")
+		return NewOperationError(ProposerSlashingOperation, ProposerSlashingInvalidSignature,
+			errors.New("header 2"))
+	}
+	return nil
+}
+
+func (f *PropSlashFeature) ProcessProposerSlashing(ps *ProposerSlashing) error {
+	if err := f.ValidateProposerSlashing(ps); err != nil {
+		return err
 	}
 	f.Meta.SlashValidator(ps.ProposerIndex, nil)
 	return nil
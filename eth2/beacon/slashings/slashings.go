@@ -29,9 +29,21 @@ type SlashingFeature struct {
 		meta.EffectiveBalances
 		meta.Slashing
 		meta.Exits
+		meta.RewardsReporting
+		meta.Observing
 	}
 }
 
+// Decreases the balance of the validator, and returns the penalty that was applied:
+// the balance does not go below zero.
+func (f *SlashingFeature) penalize(index ValidatorIndex, penalty Gwei) Gwei {
+	if balance := f.Meta.GetBalance(index); balance < penalty {
+		penalty = balance
+	}
+	f.Meta.DecreaseBalance(index, penalty)
+	return penalty
+}
+
 // Slash the validator with the given index.
 func (f *SlashingFeature) SlashValidator(slashedIndex ValidatorIndex, whistleblowerIndex *ValidatorIndex) {
 	slot := f.Meta.CurrentSlot()
@@ -46,7 +58,8 @@ func (f *SlashingFeature) SlashValidator(slashedIndex ValidatorIndex, whistleblo
 
 	f.State.Slashings[currentEpoch%EPOCHS_PER_SLASHINGS_VECTOR] += validator.EffectiveBalance
 
-	f.Meta.DecreaseBalance(slashedIndex, validator.EffectiveBalance/MIN_SLASHING_PENALTY_QUOTIENT)
+	slashingPenalty := validator.EffectiveBalance / MIN_SLASHING_PENALTY_QUOTIENT
+	slashingPenalty = f.penalize(slashedIndex, slashingPenalty)
 
 	propIndex := f.Meta.GetBeaconProposerIndex(slot)
 	if whistleblowerIndex == nil {
@@ -56,6 +69,14 @@ func (f *SlashingFeature) SlashValidator(slashedIndex ValidatorIndex, whistleblo
 	proposerReward := whistleblowerReward / PROPOSER_REWARD_QUOTIENT
 	f.Meta.IncreaseBalance(propIndex, proposerReward)
 	f.Meta.IncreaseBalance(*whistleblowerIndex, whistleblowerReward-proposerReward)
+
+	f.Meta.GetObserver().ValidatorSlashed(slashedIndex, *whistleblowerIndex)
+
+	if report := f.Meta.RewardsReport(); report != nil {
+		report.Slashing.AddPenalty(slashedIndex, slashingPenalty)
+		report.Whistleblower.AddReward(propIndex, proposerReward)
+		report.Whistleblower.AddReward(*whistleblowerIndex, whistleblowerReward-proposerReward)
+	}
 }
 
 func (f *SlashingFeature) ProcessEpochSlashings() {
@@ -77,6 +98,9 @@ func (f *SlashingFeature) ProcessEpochSlashings() {
 			penaltyNumerator *= slashingsWeight
 		}
 		penalty := penaltyNumerator / totalBalance * EFFECTIVE_BALANCE_INCREMENT
-		f.Meta.DecreaseBalance(index, penalty)
+		penalty = f.penalize(index, penalty)
+		if report := f.Meta.RewardsReport(); report != nil {
+			report.Slashing.AddPenalty(index, penalty)
+		}
 	}
 }
@@ -1,8 +1,8 @@
 package transition
 
 import (
-	"errors"
 	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
 )
 
 type BlockInput interface {
@@ -22,6 +22,7 @@ type TransitionFeature struct {
 		StateRoot() Root
 		CurrentProposer() BLSPubkey
 		CurrentVersion() Version
+		meta.Observing
 	}
 }
 
@@ -40,8 +41,10 @@ func (f *TransitionFeature) ProcessSlots(slot Slot) {
 			f.Meta.ProcessEpoch()
 		}
 		f.Meta.IncrementSlot()
+		f.Meta.GetObserver().SlotProcessed(currentSlot)
 		if isEpochEnd {
 			f.Meta.StartEpoch()
+			f.Meta.GetObserver().EpochStarted(f.Meta.CurrentSlot().ToEpoch())
 		}
 	}
 }
@@ -52,12 +55,12 @@ func (f *TransitionFeature) ProcessSlots(slot Slot) {
 //
 func (f *TransitionFeature) StateTransition(block BlockInput, validateResult bool) error {
 	if f.Meta.CurrentSlot() > block.Slot() {
-		return errors.New("cannot transition from pre-state with higher slot than transition target")
+		return NewOperationError(BlockOperation, BlockSlotInPast, nil)
 	}
 	f.ProcessSlots(block.Slot())
 	if validateResult {
 		if !block.VerifySignature(f.Meta.CurrentProposer(), f.Meta.CurrentVersion()) {
-			return errors.New("block has invalid signature")
+			return NewOperationError(BlockOperation, BlockInvalidSignature, nil)
 		}
 	}
 
@@ -67,7 +70,7 @@ func (f *TransitionFeature) StateTransition(block BlockInput, validateResult boo
 
 	// State root verification
 	if !block.VerifyStateRoot(f.Meta.StateRoot()) {
-		return errors.New("block has invalid state root")
+		return NewOperationError(BlockOperation, BlockInvalidStateRoot, nil)
 	}
 	return nil
 }
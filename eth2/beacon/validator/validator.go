@@ -32,3 +32,51 @@ func (v *Validator) IsEligibleForActivationQueue() bool {
 func (v *Validator) IsEligibleForActivation(finalizedEpoch Epoch) bool {
 	return v.ActivationEligibilityEpoch <= finalizedEpoch && v.ActivationEpoch == FAR_FUTURE_EPOCH
 }
+
+// Lifecycle status of a validator, as standardized in the beacon node API.
+type Status string
+
+const (
+	// Not eligible for the activation queue yet, e.g. waiting for more deposits.
+	PendingInitialized Status = "pending_initialized"
+	// Eligible for activation, waiting in the activation queue or for finality.
+	PendingQueued Status = "pending_queued"
+	// Active, no exit initiated.
+	ActiveOngoing Status = "active_ongoing"
+	// Active, voluntarily exiting or ejected.
+	ActiveExiting Status = "active_exiting"
+	// Active, exiting because of being slashed.
+	ActiveSlashed Status = "active_slashed"
+	// Exited, not withdrawable yet.
+	ExitedUnslashed Status = "exited_unslashed"
+	// Exited after being slashed, not withdrawable yet.
+	ExitedSlashed Status = "exited_slashed"
+	// Exited, and the balance can be withdrawn.
+	WithdrawalPossible Status = "withdrawal_possible"
+)
+
+// The lifecycle status of the validator at the given epoch.
+func (v *Validator) Status(epoch Epoch) Status {
+	if epoch < v.ActivationEpoch {
+		if v.ActivationEligibilityEpoch == FAR_FUTURE_EPOCH {
+			return PendingInitialized
+		}
+		return PendingQueued
+	}
+	if epoch < v.ExitEpoch {
+		if v.ExitEpoch == FAR_FUTURE_EPOCH {
+			return ActiveOngoing
+		}
+		if v.Slashed {
+			return ActiveSlashed
+		}
+		return ActiveExiting
+	}
+	if epoch < v.WithdrawableEpoch {
+		if v.Slashed {
+			return ExitedSlashed
+		}
+		return ExitedUnslashed
+	}
+	return WithdrawalPossible
+}
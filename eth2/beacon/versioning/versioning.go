@@ -19,6 +19,10 @@ type VersioningState struct {
 	Fork        Fork
 }
 
+func (state *VersioningState) GetGenesisTime() Timestamp {
+	return state.GenesisTime
+}
+
 // Get current slot
 func (state *VersioningState) CurrentSlot() Slot {
 	return state.Slot
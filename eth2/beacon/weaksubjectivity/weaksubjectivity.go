@@ -0,0 +1,39 @@
+package weaksubjectivity
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
+)
+
+// The weak subjectivity period for the given amount of active validators: the amount of epochs after a checkpoint,
+// after which the validator set may have changed too much, through exits and withdrawals limited by the churn limit,
+// for the checkpoint to be safe to sync from.
+func ComputeWeakSubjectivityPeriod(activeValidatorCount uint64) Epoch {
+	period := MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	if activeValidatorCount >= MIN_PER_EPOCH_CHURN_LIMIT*CHURN_LIMIT_QUOTIENT {
+		// the churn limit scales with the validator count
+		period += Epoch(SAFETY_DECAY * CHURN_LIMIT_QUOTIENT / (2 * 100))
+	} else {
+		// the churn limit is at its minimum
+		period += Epoch(SAFETY_DECAY * activeValidatorCount / (2 * 100 * MIN_PER_EPOCH_CHURN_LIMIT))
+	}
+	return period
+}
+
+type WeakSubjectivityFeature struct {
+	Meta interface {
+		meta.Versioning
+		meta.ActiveValidatorCount
+	}
+}
+
+// The weak subjectivity period, based on the active validators of the current epoch.
+func (f *WeakSubjectivityFeature) WeakSubjectivityPeriod() Epoch {
+	return ComputeWeakSubjectivityPeriod(f.Meta.GetActiveValidatorCount(f.Meta.CurrentEpoch()))
+}
+
+// Whether the checkpoint is still within the weak subjectivity period, relative to the current epoch.
+func (f *WeakSubjectivityFeature) IsWithinWeakSubjectivityPeriod(cp Checkpoint) bool {
+	currentEpoch := f.Meta.CurrentEpoch()
+	return cp.Epoch >= currentEpoch || currentEpoch-cp.Epoch <= f.WeakSubjectivityPeriod()
+}
@@ -0,0 +1,46 @@
+package chainsync
+
+import (
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zssz"
+	"os"
+	"path/filepath"
+)
+
+// Loads the blocks of a simulator dump directory (the "blocks_N.ssz" files, in order) into a block source.
+// Fork blocks are not loaded, the source keeps a single chain.
+func LoadDumpBlockSource(dir string) (*MemoryBlockSource, error) {
+	src := NewMemoryBlockSource()
+	for i := 0; ; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("blocks_%d.ssz", i))
+		block, err := readDumpBlock(p)
+		if os.IsNotExist(err) {
+			if i == 0 {
+				return nil, fmt.Errorf("no blocks in dump directory %s", dir)
+			}
+			return src, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+		src.Add(block)
+	}
+}
+
+func readDumpBlock(p string) (*phase0.SignedBeaconBlock, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	block := new(phase0.SignedBeaconBlock)
+	if err := zssz.Decode(f, uint64(info.Size()), block, phase0.SignedBeaconBlockSSZ); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
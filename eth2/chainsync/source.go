@@ -0,0 +1,69 @@
+package chainsync
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"sort"
+	"sync"
+)
+
+var ErrUnknownBlock = phase0.ErrUnknownBlock
+
+// Source of blocks to sync from, e.g. a peer or an archive.
+type BlockSource interface {
+	// Blocks with a slot in the range [start, start+count), ordered by slot. Skipped slots have no block.
+	BlocksByRange(start Slot, count uint64) ([]*phase0.SignedBeaconBlock, error)
+	// The block with the given root, or ErrUnknownBlock.
+	BlockByRoot(root Root) (*phase0.SignedBeaconBlock, error)
+}
+
+// Block source that keeps a single chain in memory. Safe for concurrent use.
+type MemoryBlockSource struct {
+	mu     sync.RWMutex
+	bySlot map[Slot]*phase0.SignedBeaconBlock
+	byRoot map[Root]*phase0.SignedBeaconBlock
+}
+
+func NewMemoryBlockSource(blocks ...*phase0.SignedBeaconBlock) *MemoryBlockSource {
+	src := &MemoryBlockSource{
+		bySlot: make(map[Slot]*phase0.SignedBeaconBlock),
+		byRoot: make(map[Root]*phase0.SignedBeaconBlock),
+	}
+	for _, b := range blocks {
+		src.Add(b)
+	}
+	return src
+}
+
+// Adds the block, replacing any block at the same slot.
+func (src *MemoryBlockSource) Add(block *phase0.SignedBeaconBlock) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.bySlot[block.Message.Slot] = block
+	src.byRoot[ssz.HashTreeRoot(&block.Message, phase0.BeaconBlockSSZ)] = block
+}
+
+func (src *MemoryBlockSource) BlocksByRange(start Slot, count uint64) ([]*phase0.SignedBeaconBlock, error) {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	out := make([]*phase0.SignedBeaconBlock, 0)
+	for slot, b := range src.bySlot {
+		if slot >= start && uint64(slot-start) < count {
+			out = append(out, b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Message.Slot < out[j].Message.Slot
+	})
+	return out, nil
+}
+
+func (src *MemoryBlockSource) BlockByRoot(root Root) (*phase0.SignedBeaconBlock, error) {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	if b, ok := src.byRoot[root]; ok {
+		return b, nil
+	}
+	return nil, ErrUnknownBlock
+}
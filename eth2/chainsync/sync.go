@@ -0,0 +1,165 @@
+package chainsync
+
+import (
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forks"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+)
+
+// Syncs a state by replaying the blocks of a block source on top of it.
+type RangeSync struct {
+	// The state to apply blocks to. Replaced with a snapshot when a batch fails.
+	// Events passed to the observer of the state, while applying a batch that fails, are not rolled back.
+	State  *phase0.FullFeaturedState
+	Source BlockSource
+	// Other sources of the same chain, e.g. other peers. Failed or invalid batches are requested again
+	// from the next source, rotating through the source and the peers.
+	Peers []BlockSource
+	// Amount of slots to request per batch
+	BatchSize uint64
+	// Amount of times a failed or invalid batch is requested again, before giving up
+	MaxRetries int
+	// Verify block signatures and state roots
+	ValidateResult bool
+	// The forks to upgrade the state with, at the fork epochs. Nil for a chain with only the phase0 fork.
+	Forks *forks.Registry
+}
+
+func NewRangeSync(state *phase0.FullFeaturedState, source BlockSource) *RangeSync {
+	return &RangeSync{
+		State:          state,
+		Source:         source,
+		BatchSize:      uint64(SLOTS_PER_EPOCH),
+		MaxRetries:     3,
+		ValidateResult: true,
+	}
+}
+
+// Checks that the blocks are ordered, within the slot range, and build on top of each other, starting from the parent.
+func checkBatch(blocks []*phase0.SignedBeaconBlock, start Slot, count uint64, parent Root) error {
+	prevSlot := start
+	for i, b := range blocks {
+		slot := b.Message.Slot
+		if slot < start || uint64(slot-start) >= count {
+			return fmt.Errorf("block %d at slot %d is outside of requested range", i, slot)
+		}
+		if i > 0 && slot <= prevSlot {
+			return fmt.Errorf("block %d at slot %d is not after previous block at slot %d", i, slot, prevSlot)
+		}
+		if b.Message.ParentRoot != parent {
+			return fmt.Errorf("block %d at slot %d has parent %x, expected %x", i, slot, b.Message.ParentRoot, parent)
+		}
+		parent = ssz.HashTreeRoot(&b.Message, phase0.BeaconBlockSSZ)
+		prevSlot = slot
+	}
+	return nil
+}
+
+// Applies the blocks to the state. The state is left in an undefined state if an error is returned.
+func (s *RangeSync) applyBlocks(blocks []*phase0.SignedBeaconBlock) error {
+	for _, b := range blocks {
+		state, err := s.Forks.Phase0StateTransition(s.State, b, s.ValidateResult)
+		if err != nil {
+			return fmt.Errorf("block at slot %d failed to transition: %w", b.Message.Slot, err)
+		}
+		s.State = state
+	}
+	return nil
+}
+
+// The source to use for the given attempt of a request.
+func (s *RangeSync) source(attempt int) BlockSource {
+	if n := len(s.Peers) + 1; attempt%n != 0 {
+		return s.Peers[attempt%n-1]
+	}
+	return s.Source
+}
+
+// Applies the blocks to the state, restoring the state to what it was before the blocks on failure.
+// The rollback does not undo the events already passed to the observer of the state.
+func (s *RangeSync) applyOrRollback(blocks []*phase0.SignedBeaconBlock) error {
+	pre, err := s.State.Copy()
+	if err != nil {
+		return err
+	}
+	if err := s.applyBlocks(blocks); err != nil {
+		pre.Observer = s.State.Observer
+		s.State = pre
+		return err
+	}
+	return nil
+}
+
+// Fetches, checks and applies the batch of slots, from the given source.
+// On failure the state is restored to what it was before the batch.
+func (s *RangeSync) syncBatchFrom(src BlockSource, start Slot, count uint64) error {
+	blocks, err := src.BlocksByRange(start, count)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	if err := checkBatch(blocks, start, count, s.State.HeadRoot()); err != nil {
+		return fmt.Errorf("invalid batch: %w", err)
+	}
+	if len(blocks) == 0 {
+		// all slots skipped
+		return nil
+	}
+	if err := s.applyOrRollback(blocks); err != nil {
+		return fmt.Errorf("invalid batch: %w", err)
+	}
+	return nil
+}
+
+// Syncs the batch of slots, repeating failed and invalid requests with the next source:
+// errors of a source, like a lost connection, may be temporary, and other peers may serve a valid batch.
+func (s *RangeSync) syncBatch(start Slot, count uint64) error {
+	var lastErr error
+	for attempt := 0; attempt <= s.MaxRetries; attempt++ {
+		lastErr = s.syncBatchFrom(s.source(attempt), start, count)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("sync of %d slots from slot %d failed after %d retries: %w", count, start, s.MaxRetries, lastErr)
+}
+
+// Syncs the state up to and including the target slot, batch by batch.
+// The state ends at the slot of the last applied block.
+func (s *RangeSync) SyncTo(target Slot) error {
+	for start := s.State.Slot + 1; start <= target; {
+		count := s.BatchSize
+		if remaining := uint64(target-start) + 1; remaining < count {
+			count = remaining
+		}
+		if err := s.syncBatch(start, count); err != nil {
+			return err
+		}
+		start += Slot(count)
+	}
+	return nil
+}
+
+// Syncs the chain up to the block with the given root, by walking back parents with the source,
+// until the current head of the state is found. Useful for heads announced by peers.
+func (s *RangeSync) SyncToRoot(root Root) error {
+	head := s.State.HeadRoot()
+	var chain []*phase0.SignedBeaconBlock
+	for root != head {
+		b, err := s.Source.BlockByRoot(root)
+		if err != nil {
+			return fmt.Errorf("could not get block %x: %w", root, err)
+		}
+		if b.Message.Slot <= s.State.Slot {
+			return fmt.Errorf("block %x at slot %d does not descend from the head of the state", root, b.Message.Slot)
+		}
+		chain = append(chain, b)
+		root = b.Message.ParentRoot
+	}
+	// reverse, to apply the oldest block first
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return s.applyOrRollback(chain)
+}
@@ -7,3 +7,6 @@ const BASE_REWARDS_PER_EPOCH = 4
 const DEPOSIT_CONTRACT_TREE_DEPTH = 32
 
 const SECONDS_PER_DAY = 24 * 60 * 60
+
+// Weak subjectivity: the tolerated decay of the safety margin of a checkpoint, in percent
+const SAFETY_DECAY = 10
@@ -1,10 +1,20 @@
 package core
 
+import "github.com/protolambda/zssz"
+
+type GweiList []Gwei
+
+func (*GweiList) Limit() uint64 {
+	return VALIDATOR_REGISTRY_LIMIT
+}
+
+var DeltasSSZ = zssz.GetSSZ((*Deltas)(nil))
+
 type Deltas struct {
 	// element for each validator in registry
-	Rewards []Gwei
+	Rewards GweiList
 	// element for each validator in registry
-	Penalties []Gwei
+	Penalties GweiList
 }
 
 func NewDeltas(validatorCount uint64) *Deltas {
@@ -14,11 +24,34 @@ func NewDeltas(validatorCount uint64) *Deltas {
 	}
 }
 
+// Extends the deltas with zeroes, to cover the given amount of validators.
+func (deltas *Deltas) Grow(validatorCount uint64) {
+	if extra := int(validatorCount) - len(deltas.Rewards); extra > 0 {
+		deltas.Rewards = append(deltas.Rewards, make([]Gwei, extra, extra)...)
+	}
+	if extra := int(validatorCount) - len(deltas.Penalties); extra > 0 {
+		deltas.Penalties = append(deltas.Penalties, make([]Gwei, extra, extra)...)
+	}
+}
+
+// Adds the other deltas, growing the deltas if the other deltas cover more validators.
 func (deltas *Deltas) Add(other *Deltas) {
-	for i := 0; i < len(deltas.Rewards); i++ {
-		deltas.Rewards[i] += other.Rewards[i]
+	deltas.Grow(uint64(len(other.Rewards)))
+	deltas.Grow(uint64(len(other.Penalties)))
+	for i, v := range other.Rewards {
+		deltas.Rewards[i] += v
 	}
-	for i := 0; i < len(deltas.Penalties); i++ {
-		deltas.Penalties[i] += other.Penalties[i]
+	for i, v := range other.Penalties {
+		deltas.Penalties[i] += v
 	}
 }
+
+func (deltas *Deltas) AddReward(index ValidatorIndex, v Gwei) {
+	deltas.Grow(uint64(index) + 1)
+	deltas.Rewards[index] += v
+}
+
+func (deltas *Deltas) AddPenalty(index ValidatorIndex, v Gwei) {
+	deltas.Grow(uint64(index) + 1)
+	deltas.Penalties[index] += v
+}
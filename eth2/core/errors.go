@@ -0,0 +1,199 @@
+package core
+
+import "fmt"
+
+// The part of a block that failed to be processed.
+type OperationKind uint8
+
+const (
+	// The block as a whole: its slot, signature and resulting state root
+	BlockOperation OperationKind = iota
+	HeaderOperation
+	RandaoOperation
+	Eth1VoteOperation
+	ProposerSlashingOperation
+	AttesterSlashingOperation
+	AttestationOperation
+	DepositOperation
+	VoluntaryExitOperation
+)
+
+var operationKindNames = [...]string{
+	BlockOperation:            "block",
+	HeaderOperation:           "block header",
+	RandaoOperation:           "randao reveal",
+	Eth1VoteOperation:         "eth1 vote",
+	ProposerSlashingOperation: "proposer slashing",
+	AttesterSlashingOperation: "attester slashing",
+	AttestationOperation:      "attestation",
+	DepositOperation:          "deposit",
+	VoluntaryExitOperation:    "voluntary exit",
+}
+
+func (k OperationKind) String() string {
+	if int(k) < len(operationKindNames) {
+		return operationKindNames[k]
+	}
+	return fmt.Sprintf("operation kind %d", uint8(k))
+}
+
+// The reason why a block or operation was rejected. A reason code is an error itself,
+// to check for with errors.Is, also when wrapped in an OperationError.
+type ReasonCode uint16
+
+const (
+	UnknownReason ReasonCode = iota
+
+	BlockSlotInPast
+	BlockInvalidSignature
+	BlockInvalidStateRoot
+
+	HeaderSlotMismatch
+	HeaderParentRootMismatch
+	HeaderProposerSlashed
+
+	RandaoInvalidReveal
+
+	Eth1VotesExceeded
+
+	ProposerSlashingInvalidIndex
+	ProposerSlashingSlotMismatch
+	ProposerSlashingSameHeaders
+	ProposerSlashingNotSlashable
+	ProposerSlashingInvalidSignature
+
+	AttesterSlashingNotSlashable
+	AttesterSlashingInvalidAttestation
+	AttesterSlashingNoEffect
+
+	AttestationSlotTooOld
+	AttestationSlotTooNew
+	AttestationTargetTooOld
+	AttestationTargetInFuture
+	AttestationTargetEpochMismatch
+	AttestationCommitteeIndexOutOfRange
+	AttestationSourceMismatch
+	AttestationBitsSizeMismatch
+	AttestationInvalidIndexed
+
+	IndexedAttestationInvalidCount
+	IndexedAttestationUnsorted
+	IndexedAttestationDuplicateIndex
+	IndexedAttestationIndexOutOfRange
+	IndexedAttestationInvalidSignature
+
+	DepositCountMismatch
+	DepositInvalidProof
+
+	ExitInvalidIndex
+	ExitValidatorInactive
+	ExitAlreadyExited
+	ExitEpochInFuture
+	ExitTooSoon
+	ExitInvalidSignature
+)
+
+var reasonDescriptions = [...]string{
+	UnknownReason: "unknown reason",
+
+	BlockSlotInPast:       "cannot transition from pre-state with higher slot than transition target",
+	BlockInvalidSignature: "block has invalid signature",
+	BlockInvalidStateRoot: "block has invalid state root",
+
+	HeaderSlotMismatch:       "slot of block does not match slot of state",
+	HeaderParentRootMismatch: "previous block root does not match root from latest state block header",
+	HeaderProposerSlashed:    "cannot accept block header from slashed proposer",
+
+	RandaoInvalidReveal: "randao invalid",
+
+	Eth1VotesExceeded: "cannot process Eth1 vote, already voted maximum times",
+
+	ProposerSlashingInvalidIndex:     "invalid proposer index",
+	ProposerSlashingSlotMismatch:     "proposer slashing requires slashing headers to have the same slot",
+	ProposerSlashingSameHeaders:      "proposer slashing requires two different headers",
+	ProposerSlashingNotSlashable:     "proposer slashing requires proposer to be slashable",
+	ProposerSlashingInvalidSignature: "proposer slashing header has invalid BLS signature",
+
+	AttesterSlashingNotSlashable:       "attester slashing has no valid reasoning",
+	AttesterSlashingInvalidAttestation: "attestation of attester slashing cannot be verified",
+	AttesterSlashingNoEffect:           "attester slashing is not effective, hence invalid",
+
+	AttestationSlotTooOld:               "attestation slot is too old",
+	AttestationSlotTooNew:               "attestation is too new",
+	AttestationTargetTooOld:             "attestation data is invalid, target is too far in past",
+	AttestationTargetInFuture:           "attestation data is invalid, target is in future",
+	AttestationTargetEpochMismatch:      "attestation data is invalid, slot epoch does not match target epoch",
+	AttestationCommitteeIndexOutOfRange: "attestation data is invalid, committee index out of range",
+	AttestationSourceMismatch:           "attestation source does not match justified checkpoint",
+	AttestationBitsSizeMismatch:         "committee size does not match bits size",
+	AttestationInvalidIndexed:           "attestation could not be verified in its indexed form",
+
+	IndexedAttestationInvalidCount:     "invalid indices count in indexed attestation",
+	IndexedAttestationUnsorted:         "attestation indices are not sorted",
+	IndexedAttestationDuplicateIndex:   "attestation indices are duplicate",
+	IndexedAttestationIndexOutOfRange:  "attestation indices contains out of range index",
+	IndexedAttestationInvalidSignature: "could not verify BLS signature for indexed attestation",
+
+	DepositCountMismatch: "block does not contain expected deposits amount",
+	DepositInvalidProof:  "deposit merkle proof failed to be verified",
+
+	ExitInvalidIndex:      "invalid exit validator index",
+	ExitValidatorInactive: "validator must be active to be able to voluntarily exit",
+	ExitAlreadyExited:     "validator already exited",
+	ExitEpochInFuture:     "invalid exit epoch",
+	ExitTooSoon:           "exit is too soon",
+	ExitInvalidSignature:  "voluntary exit signature could not be verified",
+}
+
+func (r ReasonCode) Error() string {
+	if int(r) < len(reasonDescriptions) {
+		return reasonDescriptions[r]
+	}
+	return fmt.Sprintf("reason code %d", uint16(r))
+}
+
+// Error of a block or operation that was rejected during the state transition.
+// Use errors.As to retrieve it, and errors.Is to check for a ReasonCode.
+type OperationError struct {
+	Kind OperationKind
+	// Index of the operation within its list in the block body. Zero for parts of the block that are not a list.
+	Index  int
+	Reason ReasonCode
+	// Optional, the underlying error, with more details about the rejection.
+	Cause error
+}
+
+func NewOperationError(kind OperationKind, reason ReasonCode, cause error) *OperationError {
+	return &OperationError{Kind: kind, Reason: reason, Cause: cause}
+}
+
+func (e *OperationError) Error() string {
+	msg := e.Kind.String()
+	switch e.Kind {
+	case ProposerSlashingOperation, AttesterSlashingOperation, AttestationOperation,
+		DepositOperation, VoluntaryExitOperation:
+		msg += fmt.Sprintf(" %d", e.Index)
+	}
+	msg += ": " + e.Reason.Error()
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *OperationError) Is(target error) bool {
+	reason, ok := target.(ReasonCode)
+	return ok && reason == e.Reason
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Cause
+}
+
+// Sets the index of the operation within its list in the block body, if the error is an operation error.
+func WithOperationIndex(err error, index int) error {
+	if opErr, ok := err.(*OperationError); ok {
+		opErr.Index = index
+	}
+	return err
+}
@@ -22,6 +22,13 @@ const SHUFFLE_ROUND_COUNT uint8 = generated.SHUFFLE_ROUND_COUNT
 const MIN_GENESIS_ACTIVE_VALIDATOR_COUNT = generated.MIN_GENESIS_ACTIVE_VALIDATOR_COUNT
 const MIN_GENESIS_TIME = generated.MIN_GENESIS_TIME
 
+// Validator
+const ETH1_FOLLOW_DISTANCE uint64 = generated.ETH1_FOLLOW_DISTANCE
+const TARGET_AGGREGATORS_PER_COMMITTEE = generated.TARGET_AGGREGATORS_PER_COMMITTEE
+
+// Fork choice
+const SAFE_SLOTS_TO_UPDATE_JUSTIFIED Slot = generated.SAFE_SLOTS_TO_UPDATE_JUSTIFIED
+
 // Gwei values
 const MIN_DEPOSIT_AMOUNT Gwei = generated.MIN_DEPOSIT_AMOUNT
 const MAX_EFFECTIVE_BALANCE Gwei = generated.MAX_EFFECTIVE_BALANCE
@@ -40,6 +47,7 @@ const BLS_WITHDRAWAL_PREFIX byte = generated.BLS_WITHDRAWAL_PREFIX
 
 // Time parameters
 const SECONDS_PER_SLOT Timestamp = generated.SECONDS_PER_SLOT
+const SECONDS_PER_ETH1_BLOCK Timestamp = generated.SECONDS_PER_ETH1_BLOCK
 const MIN_ATTESTATION_INCLUSION_DELAY Slot = generated.MIN_ATTESTATION_INCLUSION_DELAY
 const SLOTS_PER_EPOCH Slot = generated.SLOTS_PER_EPOCH
 const MIN_SEED_LOOKAHEAD Epoch = generated.MIN_SEED_LOOKAHEAD
@@ -78,6 +86,11 @@ var (
 	DOMAIN_BEACON_ATTESTER BLSDomainType = parseDomain(generated.DOMAIN_BEACON_ATTESTER)
 	DOMAIN_DEPOSIT         BLSDomainType = parseDomain(generated.DOMAIN_DEPOSIT)
 	DOMAIN_VOLUNTARY_EXIT  BLSDomainType = parseDomain(generated.DOMAIN_VOLUNTARY_EXIT)
+
+	// Aggregation domains, of the v0.11 validator spec, like the aggregation containers.
+	// Not part of the v0.9.3 configurations.
+	DOMAIN_SELECTION_PROOF     BLSDomainType = parseDomain(generated.DOMAIN_SELECTION_PROOF)
+	DOMAIN_AGGREGATE_AND_PROOF BLSDomainType = parseDomain(generated.DOMAIN_AGGREGATE_AND_PROOF)
 )
 
 func parseDomain(v uint32) (out BLSDomainType) {
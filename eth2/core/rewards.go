@@ -0,0 +1,76 @@
+package core
+
+// Breakdown of the rewards and penalties of validators, per component.
+// Components are accumulated over all transitions that the report is attached to.
+type RewardsReport struct {
+	// Attestation rewards and penalties for the FFG source vote
+	Source Deltas
+	// Attestation rewards and penalties for the FFG target vote
+	Target Deltas
+	// Attestation rewards and penalties for the LMD-GHOST head vote
+	Head Deltas
+	// Attester rewards for the inclusion speed of their attestation
+	InclusionDelay Deltas
+	// Proposer rewards for including attestations
+	ProposerInclusion Deltas
+	// Penalties while finality is delayed (inactivity leak)
+	Inactivity Deltas
+	// Penalties of slashed validators: the initial penalty when slashed, and the correlated penalty at epoch processing
+	Slashing Deltas
+	// Whistleblower and proposer rewards for slashings
+	Whistleblower Deltas
+}
+
+// The attester and proposer inclusion rewards combined, like the inclusion-delay deltas of the spec.
+func (report *RewardsReport) InclusionDelayDeltas() *Deltas {
+	out := new(Deltas)
+	out.Add(&report.InclusionDelay)
+	out.Add(&report.ProposerInclusion)
+	return out
+}
+
+// All components combined.
+func (report *RewardsReport) Total() *Deltas {
+	out := new(Deltas)
+	for _, d := range []*Deltas{&report.Source, &report.Target, &report.Head,
+		&report.InclusionDelay, &report.ProposerInclusion, &report.Inactivity,
+		&report.Slashing, &report.Whistleblower} {
+		out.Add(d)
+	}
+	return out
+}
+
+// Lowers the recorded attestation penalties of the validator by the given amount,
+// for penalties that were not applied because the balance of the validator reached zero.
+// The amount is taken off the inactivity, head, target and source penalties, in that order.
+func (report *RewardsReport) ReduceAttestationPenalties(index ValidatorIndex, amount Gwei) {
+	for _, d := range []*Deltas{&report.Inactivity, &report.Head, &report.Target, &report.Source} {
+		if amount == 0 {
+			return
+		}
+		if int(index) >= len(d.Penalties) {
+			continue
+		}
+		if p := &d.Penalties[index]; *p >= amount {
+			*p -= amount
+			amount = 0
+		} else {
+			amount -= *p
+			*p = 0
+		}
+	}
+}
+
+// Deep-copies the report, so the copy can be accumulated into independently.
+func (report *RewardsReport) Copy() *RewardsReport {
+	out := new(RewardsReport)
+	out.Source.Add(&report.Source)
+	out.Target.Add(&report.Target)
+	out.Head.Add(&report.Head)
+	out.InclusionDelay.Add(&report.InclusionDelay)
+	out.ProposerInclusion.Add(&report.ProposerInclusion)
+	out.Inactivity.Add(&report.Inactivity)
+	out.Slashing.Add(&report.Slashing)
+	out.Whistleblower.Add(&report.Whistleblower)
+	return out
+}
@@ -13,6 +13,10 @@ type DepositIndex uint64
 // Current slot
 type Slot uint64
 
+func (s Slot) ToTimestamp(genesisTime Timestamp) Timestamp {
+	return genesisTime + Timestamp(s)*SECONDS_PER_SLOT
+}
+
 func (s Slot) ToEpoch() Epoch {
 	return Epoch(s / SLOTS_PER_EPOCH)
 }
@@ -0,0 +1,173 @@
+package eth1follow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	"github.com/protolambda/zrnt/eth2/beacon/eth1"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zssz/htr"
+	"github.com/protolambda/zssz/merkle"
+	"io"
+)
+
+var ErrUnknownEth1Block = errors.New("unknown eth1 block")
+
+// Follows the eth1 chain and the deposit contract logs, to provide eth1 votes and deposits for beacon blocks.
+// Not safe for concurrent use.
+type DepositFollower struct {
+	Source LogSource
+	// All deposits, ordered by deposit index
+	Deposits []DepositData
+	// The followed eth1 blocks, with the deposit root and count at the end of each block
+	Chain eth1.MemoryEth1Chain
+
+	// Roots of the deposit data, the leaves of the deposit tree
+	depositRoots phase0.DepositRoots
+	tree         DepositTree
+	byHash       map[Root]*eth1.Eth1Block
+}
+
+func NewDepositFollower(source LogSource) *DepositFollower {
+	return &DepositFollower{Source: source, byHash: make(map[Root]*eth1.Eth1Block)}
+}
+
+// Processes the eth1 block and its deposit logs. Blocks must be processed in order.
+func (f *DepositFollower) ProcessBlock(b *LogBlock) error {
+	if n := len(f.Chain.Blocks); n > 0 {
+		if last := f.Chain.Blocks[n-1]; b.Number <= last.Number || b.Timestamp < last.Timestamp {
+			return fmt.Errorf("eth1 block %d (time %d) does not follow block %d (time %d)",
+				b.Number, b.Timestamp, last.Number, last.Timestamp)
+		}
+	}
+	for i := range b.Logs {
+		if expected := DepositIndex(len(f.Deposits)) + DepositIndex(i); b.Logs[i].Index != expected {
+			return fmt.Errorf("eth1 block %d has deposit %d, expected deposit %d", b.Number, b.Logs[i].Index, expected)
+		}
+	}
+	for i := range b.Logs {
+		data := b.Logs[i].Data
+		root := ssz.HashTreeRoot(&data, DepositDataSSZ)
+		f.Deposits = append(f.Deposits, data)
+		f.depositRoots = append(f.depositRoots, root)
+		f.tree.Add(root)
+	}
+	block := &eth1.Eth1Block{
+		Hash:         b.Hash,
+		Number:       b.Number,
+		Timestamp:    b.Timestamp,
+		DepositRoot:  f.tree.Root(),
+		DepositCount: f.tree.Count(),
+	}
+	f.Chain.AddBlock(block)
+	f.byHash[block.Hash] = block
+	return nil
+}
+
+// Processes all blocks available from the source. Returns the number of processed blocks.
+func (f *DepositFollower) Follow() (n int, err error) {
+	for {
+		b, err := f.Source.NextBlock()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := f.ProcessBlock(b); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// The eth1 blocks with a timestamp in the given range, to choose eth1 votes from. See eth1.Eth1BlockProvider.
+func (f *DepositFollower) BlocksByTime(min Timestamp, max Timestamp) ([]*eth1.Eth1Block, error) {
+	return f.Chain.BlocksByTime(min, max)
+}
+
+func (f *DepositFollower) BlockByHash(hash Root) (*eth1.Eth1Block, error) {
+	if b, ok := f.byHash[hash]; ok {
+		return b, nil
+	}
+	return nil, ErrUnknownEth1Block
+}
+
+// The latest followed eth1 block, nil if no blocks were followed yet.
+func (f *DepositFollower) Head() *eth1.Eth1Block {
+	if len(f.Chain.Blocks) == 0 {
+		return nil
+	}
+	return f.Chain.Blocks[len(f.Chain.Blocks)-1]
+}
+
+// Creates the deposit at the given index, with a proof against the deposit root of the given deposit count.
+func (f *DepositFollower) Deposit(index DepositIndex, count DepositIndex) (*Deposit, error) {
+	if index >= count || count > DepositIndex(len(f.Deposits)) {
+		return nil, fmt.Errorf("cannot prove deposit %d in tree of %d deposits, only %d deposits known",
+			index, count, len(f.Deposits))
+	}
+	leaf := func(i uint64) []byte {
+		return f.depositRoots[i][:]
+	}
+	proof := merkle.ConstructProof(htr.HashFn(hashing.GetHashFn()), uint64(count), 1<<DEPOSIT_CONTRACT_TREE_DEPTH, leaf, uint64(index))
+	dep := &Deposit{Data: f.Deposits[index]}
+	for j := 0; j < DEPOSIT_CONTRACT_TREE_DEPTH; j++ {
+		dep.Proof[j] = proof[j]
+	}
+	// mix in the length
+	binary.LittleEndian.PutUint64(dep.Proof[DEPOSIT_CONTRACT_TREE_DEPTH][:], uint64(count))
+	return dep, nil
+}
+
+// The deposits a block has to include, given the eth1 data (after voting) and deposit index of the state.
+func (f *DepositFollower) BlockDeposits(eth1Data eth1.Eth1Data, depositIndex DepositIndex) (phase0.Deposits, error) {
+	end := eth1Data.DepositCount
+	if end > depositIndex+MAX_DEPOSITS {
+		end = depositIndex + MAX_DEPOSITS
+	}
+	var out phase0.Deposits
+	for i := depositIndex; i < end; i++ {
+		dep, err := f.Deposit(i, eth1Data.DepositCount)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *dep)
+	}
+	return out, nil
+}
+
+// All deposits up to and including the given eth1 block, with proofs as expected by phase0.GenesisFromEth1:
+// each deposit is proven against the deposit root of the deposits up to and including itself.
+func (f *DepositFollower) GenesisDeposits(blockHash Root) ([]Deposit, error) {
+	block, err := f.BlockByHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Deposit, 0, block.DepositCount)
+	// replay the tree, the proofs are available incrementally
+	var tree DepositTree
+	for i := DepositIndex(0); i < block.DepositCount; i++ {
+		out = append(out, Deposit{Proof: tree.NextLeafProof(), Data: f.Deposits[i]})
+		tree.Add(f.depositRoots[i])
+	}
+	return out, nil
+}
+
+// Creates the genesis state from the deposits up to and including the given eth1 block.
+// The state may not be a valid genesis state, see phase0.IsValidGenesisState.
+func (f *DepositFollower) GenesisState(blockHash Root, verifyDeposits bool) (*phase0.FullFeaturedState, error) {
+	block, err := f.BlockByHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := f.GenesisDeposits(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return phase0.GenesisFromEth1(block.Hash, block.Timestamp, deps, verifyDeposits)
+}
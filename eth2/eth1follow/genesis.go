@@ -0,0 +1,147 @@
+package eth1follow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	"github.com/protolambda/zrnt/eth2/beacon/eth1"
+	. "github.com/protolambda/zrnt/eth2/beacon/validator"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zssz"
+	"io"
+)
+
+var ErrGenesisTriggered = errors.New("genesis was already triggered")
+
+// Watches eth1 blocks for the genesis trigger. The deposits are processed incrementally into a pre-genesis state,
+// instead of recomputing the genesis state from scratch for every eth1 block like phase0.GenesisFromEth1.
+// Not safe for concurrent use.
+type Eth1GenesisWatcher struct {
+	// The number of validators that would be active if genesis was triggered now.
+	ActiveCount uint64
+	// The eth1 block that triggered genesis, nil if genesis was not triggered yet.
+	GenesisBlock *LogBlock
+
+	// All deposits processed so far, eth1 block hash and genesis time are set when genesis triggers.
+	state     *phase0.BeaconState
+	processor *DepositFeature
+	tree      DepositTree
+	// indexed by validator index, true if the validator would be active at genesis.
+	active []bool
+	// validator indices by pubkey, to find the validator of a deposit without scanning the registry.
+	indices map[BLSPubkey]ValidatorIndex
+}
+
+func NewEth1GenesisWatcher() *Eth1GenesisWatcher {
+	state := phase0.PreGenesisState(Root{}, 0, 0)
+	return &Eth1GenesisWatcher{
+		state:     state,
+		processor: phase0.GenesisDepositProcessor(state),
+		indices:   make(map[BLSPubkey]ValidatorIndex),
+	}
+}
+
+// Whether the validator would be activated at genesis, matching the effective balance update of phase0.InitState.
+func activeAtGenesis(v *Validator, balance Gwei) bool {
+	effBalance := v.EffectiveBalance
+	if balance < effBalance || effBalance+3*HALF_INCREMENT < balance {
+		effBalance = balance - (balance % EFFECTIVE_BALANCE_INCREMENT)
+		if MAX_EFFECTIVE_BALANCE < effBalance {
+			effBalance = MAX_EFFECTIVE_BALANCE
+		}
+	}
+	return effBalance == MAX_EFFECTIVE_BALANCE
+}
+
+func (w *Eth1GenesisWatcher) processDeposit(l *DepositLog) error {
+	if l.Index != w.state.DepositIndex {
+		return fmt.Errorf("expected deposit %d, got deposit %d", w.state.DepositIndex, l.Index)
+	}
+	dep := Deposit{Proof: w.tree.NextLeafProof(), Data: l.Data}
+	w.tree.Add(ssz.HashTreeRoot(&dep.Data, DepositDataSSZ))
+	w.state.Eth1Data.DepositRoot = w.tree.Root()
+	w.state.Eth1Data.DepositCount = w.tree.Count()
+	if err := w.processor.ProcessDeposit(&dep); err != nil {
+		return err
+	}
+	index, exists := w.indices[dep.Data.Pubkey]
+	if !exists {
+		if len(w.state.Validators) == len(w.indices) {
+			// invalid deposit signature, no validator was registered
+			return nil
+		}
+		// the new validator is appended to the registry
+		index = ValidatorIndex(len(w.state.Validators) - 1)
+		w.indices[dep.Data.Pubkey] = index
+	}
+	for ValidatorIndex(len(w.active)) <= index {
+		w.active = append(w.active, false)
+	}
+	wasActive := w.active[index]
+	w.active[index] = activeAtGenesis(w.state.Validators[index], w.state.Balances[index])
+	if w.active[index] && !wasActive {
+		w.ActiveCount++
+	} else if !w.active[index] && wasActive {
+		w.ActiveCount--
+	}
+	return nil
+}
+
+// Processes the eth1 block and its deposits.
+// Returns the genesis state if the block is the first to satisfy the genesis conditions, nil otherwise.
+func (w *Eth1GenesisWatcher) ProcessBlock(b *LogBlock) (*phase0.FullFeaturedState, error) {
+	if w.GenesisBlock != nil {
+		return nil, ErrGenesisTriggered
+	}
+	for i := range b.Logs {
+		if err := w.processDeposit(&b.Logs[i]); err != nil {
+			return nil, fmt.Errorf("eth1 block %d: %v", b.Number, err)
+		}
+	}
+	if phase0.ComputeGenesisTime(b.Timestamp) < MIN_GENESIS_TIME || w.ActiveCount < MIN_GENESIS_ACTIVE_VALIDATOR_COUNT {
+		return nil, nil
+	}
+	// Copy the pre-genesis state, activating the genesis validators modifies it.
+	var buf bytes.Buffer
+	if _, err := zssz.Encode(&buf, w.state, phase0.BeaconStateSSZ); err != nil {
+		return nil, err
+	}
+	state := new(phase0.BeaconState)
+	if err := zssz.Decode(&buf, uint64(buf.Len()), state, phase0.BeaconStateSSZ); err != nil {
+		return nil, err
+	}
+	state.GenesisTime = phase0.ComputeGenesisTime(b.Timestamp)
+	state.Eth1Data = eth1.Eth1Data{
+		DepositRoot:  w.tree.Root(),
+		DepositCount: w.tree.Count(),
+		BlockHash:    b.Hash,
+	}
+	// Seed RANDAO with Eth1 entropy
+	state.SeedRandao(b.Hash)
+	full, err := phase0.InitState(state)
+	if err != nil {
+		return nil, err
+	}
+	w.GenesisBlock = b
+	return full, nil
+}
+
+// Processes the blocks of the source until genesis is triggered.
+// Returns a nil state if the source has no more blocks (yet), watching can continue when it does.
+func (w *Eth1GenesisWatcher) Watch(source LogSource) (*phase0.FullFeaturedState, error) {
+	for {
+		b, err := source.NextBlock()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if state, err := w.ProcessBlock(b); err != nil || state != nil {
+			return state, err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package eth1follow
+
+import (
+	"encoding/binary"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zssz"
+	"io"
+)
+
+// A deposit event, as logged by the deposit contract.
+type DepositLog struct {
+	Index DepositIndex
+	Data  DepositData
+}
+
+type DepositLogs []DepositLog
+
+func (*DepositLogs) Limit() uint64 {
+	return 1 << DEPOSIT_CONTRACT_TREE_DEPTH
+}
+
+// An eth1 block, with the deposit contract logs of the block.
+type LogBlock struct {
+	Hash      Root
+	Number    uint64
+	Timestamp Timestamp
+	Logs      DepositLogs
+}
+
+var LogBlockSSZ = zssz.GetSSZ((*LogBlock)(nil))
+
+// A source of eth1 blocks and their deposit logs.
+type LogSource interface {
+	// Returns the next eth1 block, io.EOF if there is no next block (yet).
+	NextBlock() (*LogBlock, error)
+}
+
+// A log source from blocks in memory, e.g. for testing.
+type MemoryLogSource struct {
+	Blocks []*LogBlock
+	next   int
+}
+
+func NewMemoryLogSource(blocks ...*LogBlock) *MemoryLogSource {
+	return &MemoryLogSource{Blocks: blocks}
+}
+
+func (s *MemoryLogSource) Add(blocks ...*LogBlock) {
+	s.Blocks = append(s.Blocks, blocks...)
+}
+
+func (s *MemoryLogSource) NextBlock() (*LogBlock, error) {
+	if s.next >= len(s.Blocks) {
+		return nil, io.EOF
+	}
+	b := s.Blocks[s.next]
+	s.next++
+	return b, nil
+}
+
+// A log source reading from a stream of SSZ encoded blocks, each prefixed with a 4 byte little-endian length.
+// See WriteLogBlock to create such a stream, e.g. a file.
+type FileLogSource struct {
+	r io.Reader
+}
+
+func NewFileLogSource(r io.Reader) *FileLogSource {
+	return &FileLogSource{r: r}
+}
+
+func (s *FileLogSource) NextBlock() (*LogBlock, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(s.r, prefix[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("incomplete log block length prefix: %v", err)
+		}
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(prefix[:])
+	b := new(LogBlock)
+	if err := zssz.Decode(io.LimitReader(s.r, int64(size)), uint64(size), b, LogBlockSSZ); err != nil {
+		return nil, fmt.Errorf("failed to decode log block: %v", err)
+	}
+	return b, nil
+}
+
+// Writes the block to a stream that can be read by a FileLogSource.
+func WriteLogBlock(w io.Writer, b *LogBlock) error {
+	var prefix [4]byte
+	binary.LittleEndian.PutUint32(prefix[:], uint32(zssz.SizeOf(b, LogBlockSSZ)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := zssz.Encode(w, b, LogBlockSSZ)
+	return err
+}
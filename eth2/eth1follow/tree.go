@@ -0,0 +1,66 @@
+package eth1follow
+
+import (
+	"encoding/binary"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"github.com/protolambda/zssz/htr"
+)
+
+// The incremental merkle tree of the deposit contract:
+// only the branch of the last leaf is kept, to compute the deposit root in O(depth) per deposit.
+type DepositTree struct {
+	branch [DEPOSIT_CONTRACT_TREE_DEPTH]Root
+	count  DepositIndex
+}
+
+// Adds the root of the next deposit data to the tree.
+func (t *DepositTree) Add(leaf Root) {
+	hash := hashing.GetHashFn()
+	t.count++
+	size := t.count
+	node := leaf
+	for h := 0; h < DEPOSIT_CONTRACT_TREE_DEPTH; h++ {
+		if size&1 == 1 {
+			t.branch[h] = node
+			return
+		}
+		node = hash.Combi(t.branch[h], node)
+		size >>= 1
+	}
+}
+
+func (t *DepositTree) Count() DepositIndex {
+	return t.count
+}
+
+// The deposit root, including the mixed in deposit count, like the deposit contract and the SSZ deposit list.
+func (t *DepositTree) Root() Root {
+	hash := hashing.GetHashFn()
+	var node Root
+	size := t.count
+	for h := 0; h < DEPOSIT_CONTRACT_TREE_DEPTH; h++ {
+		if size&1 == 1 {
+			node = hash.Combi(t.branch[h], node)
+		} else {
+			node = hash.Combi(node, htr.ZeroHashes[h])
+		}
+		size >>= 1
+	}
+	return htr.HashFn(hash).MixIn(node, uint64(t.count))
+}
+
+// The proof of the next leaf, against the root of the tree after adding the leaf.
+// The right-hand siblings of the next leaf are all empty, the left-hand siblings are in the branch.
+func (t *DepositTree) NextLeafProof() (out [DEPOSIT_CONTRACT_TREE_DEPTH + 1]Root) {
+	index := t.count
+	for h := 0; h < DEPOSIT_CONTRACT_TREE_DEPTH; h++ {
+		if (index>>uint(h))&1 == 1 {
+			out[h] = t.branch[h]
+		} else {
+			out[h] = htr.ZeroHashes[h]
+		}
+	}
+	binary.LittleEndian.PutUint64(out[DEPOSIT_CONTRACT_TREE_DEPTH][:], uint64(index+1))
+	return
+}
@@ -0,0 +1,279 @@
+package forkchoice
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	"github.com/protolambda/zrnt/eth2/beacon/header"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forks"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+)
+
+var (
+	ErrUnknownBlock      = errors.New("unknown block")
+	ErrFutureBlock       = errors.New("block is from the future")
+	ErrNotFinalized      = errors.New("block does not descend from the finalized checkpoint")
+	ErrInvalidTarget     = errors.New("attestation target is invalid")
+	ErrFutureAttestation = errors.New("attestation is from the future")
+)
+
+type LatestMessage struct {
+	Epoch Epoch
+	Root  Root
+}
+
+// The fork choice store, following the LMD-GHOST fork choice of the spec.
+// Not safe for concurrent use.
+type Store struct {
+	Time                    Timestamp
+	GenesisTime             Timestamp
+	JustifiedCheckpoint     Checkpoint
+	FinalizedCheckpoint     Checkpoint
+	BestJustifiedCheckpoint Checkpoint
+	Blocks                  map[Root]*phase0.BeaconBlock
+	BlockStates             map[Root]*phase0.FullFeaturedState
+	CheckpointStates        map[Checkpoint]*phase0.FullFeaturedState
+	LatestMessages          map[ValidatorIndex]LatestMessage
+	// The forks to upgrade states with, at the fork epochs. Nil for a chain with only the phase0 fork.
+	Forks *forks.Registry
+}
+
+// Creates a store anchored at the given state, e.g. the genesis state.
+func NewStore(anchor *phase0.FullFeaturedState) *Store {
+	anchorHeader := anchor.LatestBlockHeader
+	if anchorHeader.StateRoot == (Root{}) {
+		anchorHeader.StateRoot = anchor.StateRoot()
+	}
+	anchorRoot := ssz.HashTreeRoot(&anchorHeader, header.BeaconBlockHeaderSSZ)
+	// The anchor block body is unknown, only the header data is tracked.
+	anchorBlock := &phase0.BeaconBlock{
+		Slot:       anchorHeader.Slot,
+		ParentRoot: anchorHeader.ParentRoot,
+		StateRoot:  anchorHeader.StateRoot,
+	}
+	anchorEpoch := anchor.CurrentEpoch()
+	cp := Checkpoint{Epoch: anchorEpoch, Root: anchorRoot}
+	return &Store{
+		Time:                    anchor.GenesisTime + Timestamp(anchor.Slot)*SECONDS_PER_SLOT,
+		GenesisTime:             anchor.GenesisTime,
+		JustifiedCheckpoint:     cp,
+		FinalizedCheckpoint:     cp,
+		BestJustifiedCheckpoint: cp,
+		Blocks:                  map[Root]*phase0.BeaconBlock{anchorRoot: anchorBlock},
+		BlockStates:             map[Root]*phase0.FullFeaturedState{anchorRoot: anchor},
+		CheckpointStates:        map[Checkpoint]*phase0.FullFeaturedState{cp: anchor},
+		LatestMessages:          make(map[ValidatorIndex]LatestMessage),
+	}
+}
+
+func (s *Store) CurrentSlot() Slot {
+	if s.Time < s.GenesisTime {
+		return GENESIS_SLOT
+	}
+	return GENESIS_SLOT + Slot((s.Time-s.GenesisTime)/SECONDS_PER_SLOT)
+}
+
+// The ancestor of the block with the given root at the given slot,
+// or the latest ancestor before the slot if the slot was skipped. Zero if the block is unknown.
+func (s *Store) GetAncestor(root Root, slot Slot) Root {
+	for {
+		block, ok := s.Blocks[root]
+		if !ok {
+			return Root{}
+		}
+		if block.Slot <= slot {
+			return root
+		}
+		root = block.ParentRoot
+	}
+}
+
+// The state at the start of the epoch of the checkpoint, on top of the checkpoint block.
+func (s *Store) checkpointState(cp Checkpoint) (*phase0.FullFeaturedState, error) {
+	if state, ok := s.CheckpointStates[cp]; ok {
+		return state, nil
+	}
+	base, ok := s.BlockStates[cp.Root]
+	if !ok {
+		return nil, fmt.Errorf("checkpoint block %x: %w", cp.Root, ErrUnknownBlock)
+	}
+	state, err := base.Copy()
+	if err != nil {
+		return nil, err
+	}
+	state, err = s.Forks.ProcessPhase0Slots(state, cp.Epoch.GetStartSlot())
+	if err != nil {
+		return nil, err
+	}
+	s.CheckpointStates[cp] = state
+	return state, nil
+}
+
+func (s *Store) GetLatestAttestingBalance(root Root) (Gwei, error) {
+	state, err := s.checkpointState(s.JustifiedCheckpoint)
+	if err != nil {
+		return 0, err
+	}
+	slot := s.Blocks[root].Slot
+	balance := Gwei(0)
+	for _, i := range state.GetActiveValidatorIndices(state.CurrentEpoch()) {
+		if msg, ok := s.LatestMessages[i]; ok && s.GetAncestor(msg.Root, slot) == root {
+			balance += state.Validators[i].EffectiveBalance
+		}
+	}
+	return balance, nil
+}
+
+// The head of the chain, by LMD-GHOST, starting from the justified checkpoint.
+func (s *Store) GetHead() (Root, error) {
+	head := s.JustifiedCheckpoint.Root
+	justifiedSlot := s.JustifiedCheckpoint.Epoch.GetStartSlot()
+	for {
+		var best Root
+		var bestBalance Gwei
+		found := false
+		for root, b := range s.Blocks {
+			if b.ParentRoot != head || b.Slot <= justifiedSlot || root == head {
+				continue
+			}
+			balance, err := s.GetLatestAttestingBalance(root)
+			if err != nil {
+				return Root{}, err
+			}
+			// ties are broken by the highest root
+			if !found || balance > bestBalance || (balance == bestBalance && rootGreater(root, best)) {
+				best, bestBalance, found = root, balance, true
+			}
+		}
+		if !found {
+			return head, nil
+		}
+		head = best
+	}
+}
+
+func rootGreater(a Root, b Root) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return false
+}
+
+func (s *Store) shouldUpdateJustifiedCheckpoint(cp Checkpoint) bool {
+	if s.CurrentSlot()%SLOTS_PER_EPOCH < SAFE_SLOTS_TO_UPDATE_JUSTIFIED {
+		return true
+	}
+	newJustified, ok := s.Blocks[cp.Root]
+	if !ok || newJustified.Slot <= s.JustifiedCheckpoint.Epoch.GetStartSlot() {
+		return false
+	}
+	justifiedSlot := s.Blocks[s.JustifiedCheckpoint.Root].Slot
+	return s.GetAncestor(cp.Root, justifiedSlot) == s.JustifiedCheckpoint.Root
+}
+
+func (s *Store) OnTick(time Timestamp) {
+	previousSlot := s.CurrentSlot()
+	s.Time = time
+	currentSlot := s.CurrentSlot()
+	// the best justified checkpoint is only adopted at the start of an epoch.
+	if !(currentSlot > previousSlot && currentSlot%SLOTS_PER_EPOCH == 0) {
+		return
+	}
+	if s.BestJustifiedCheckpoint.Epoch > s.JustifiedCheckpoint.Epoch {
+		s.JustifiedCheckpoint = s.BestJustifiedCheckpoint
+	}
+}
+
+func (s *Store) OnBlock(signedBlock *phase0.SignedBeaconBlock) error {
+	block := &signedBlock.Message
+	preState, ok := s.BlockStates[block.ParentRoot]
+	if !ok {
+		return fmt.Errorf("parent %x: %w", block.ParentRoot, ErrUnknownBlock)
+	}
+	if s.CurrentSlot() < block.Slot {
+		return ErrFutureBlock
+	}
+	finalizedSlot := s.FinalizedCheckpoint.Epoch.GetStartSlot()
+	if block.Slot <= finalizedSlot {
+		return ErrNotFinalized
+	}
+	// check the ancestry through the parent, the block itself is not added until it is valid.
+	if s.GetAncestor(block.ParentRoot, finalizedSlot) != s.FinalizedCheckpoint.Root {
+		return ErrNotFinalized
+	}
+	state, err := preState.Copy()
+	if err != nil {
+		return err
+	}
+	state, err = s.Forks.Phase0StateTransition(state, signedBlock, true)
+	if err != nil {
+		return err
+	}
+	root := ssz.HashTreeRoot(block, phase0.BeaconBlockSSZ)
+	s.Blocks[root] = block
+	s.BlockStates[root] = state
+
+	if state.CurrentJustifiedCheckpoint.Epoch > s.JustifiedCheckpoint.Epoch {
+		s.BestJustifiedCheckpoint = state.CurrentJustifiedCheckpoint
+		if s.shouldUpdateJustifiedCheckpoint(state.CurrentJustifiedCheckpoint) {
+			s.JustifiedCheckpoint = state.CurrentJustifiedCheckpoint
+		}
+	}
+	if state.FinalizedCheckpoint.Epoch > s.FinalizedCheckpoint.Epoch {
+		s.FinalizedCheckpoint = state.FinalizedCheckpoint
+	}
+	return nil
+}
+
+func (s *Store) OnAttestation(attestation *Attestation) error {
+	data := &attestation.Data
+	target := data.Target
+	currentEpoch := s.CurrentSlot().ToEpoch()
+	if target.Epoch != currentEpoch && target.Epoch != currentEpoch.Previous() {
+		return fmt.Errorf("target epoch %d is not the current or previous epoch: %w", target.Epoch, ErrInvalidTarget)
+	}
+	if target.Epoch != data.Slot.ToEpoch() {
+		return fmt.Errorf("target epoch %d does not match slot %d: %w", target.Epoch, data.Slot, ErrInvalidTarget)
+	}
+	if _, ok := s.Blocks[target.Root]; !ok {
+		return fmt.Errorf("target %x: %w", target.Root, ErrUnknownBlock)
+	}
+	if s.Time < s.GenesisTime+Timestamp(target.Epoch.GetStartSlot())*SECONDS_PER_SLOT {
+		return ErrFutureAttestation
+	}
+	headBlock, ok := s.Blocks[data.BeaconBlockRoot]
+	if !ok {
+		return fmt.Errorf("head %x: %w", data.BeaconBlockRoot, ErrUnknownBlock)
+	}
+	if headBlock.Slot > data.Slot {
+		return fmt.Errorf("head block at slot %d is newer than attestation slot %d: %w", headBlock.Slot, data.Slot, ErrInvalidTarget)
+	}
+	targetState, err := s.checkpointState(target)
+	if err != nil {
+		return err
+	}
+	if uint64(data.Index) >= targetState.GetCommitteeCountAtSlot(data.Slot) {
+		return fmt.Errorf("committee index %d out of range: %w", data.Index, ErrInvalidTarget)
+	}
+	// attestations can only affect the fork choice of subsequent slots.
+	if s.CurrentSlot() < data.Slot+1 {
+		return ErrFutureAttestation
+	}
+	indexed, err := attestation.ConvertToIndexed(targetState.GetBeaconCommittee(data.Slot, data.Index))
+	if err != nil {
+		return err
+	}
+	if err := indexed.Validate(targetState); err != nil {
+		return err
+	}
+	for _, i := range indexed.AttestingIndices {
+		if msg, ok := s.LatestMessages[i]; !ok || target.Epoch > msg.Epoch {
+			s.LatestMessages[i] = LatestMessage{Epoch: target.Epoch, Root: data.BeaconBlockRoot}
+		}
+	}
+	return nil
+}
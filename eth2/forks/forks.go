@@ -0,0 +1,161 @@
+package forks
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"io"
+)
+
+// A beacon state of any fork, as returned by the type family of the fork.
+type State interface {
+	CurrentSlot() Slot
+	CurrentVersion() Version
+	GetDomain(dom BLSDomainType, messageEpoch Epoch) BLSDomain
+	ProcessSlots(slot Slot)
+	StateRoot() Root
+	// Processes a signed block of the same fork as the state, including the slot transitions up to the block.
+	StateTransition(block Block, validateResult bool) error
+	Encode(w io.Writer) error
+}
+
+// A signed beacon block of any fork, as returned by the type family of the fork.
+type Block interface {
+	Slot() Slot
+	Encode(w io.Writer) error
+}
+
+// The state and block types of a fork, and how to get to them.
+type TypeFamily interface {
+	DecodeState(r io.Reader, length uint64) (State, error)
+	DecodeBlock(r io.Reader, length uint64) (Block, error)
+	// Upgrades a state of the previous fork, at the start slot of the fork epoch, to a state of the given fork.
+	// The fork versions of the state are updated as part of the upgrade.
+	Upgrade(pre State, fork *Fork) (State, error)
+}
+
+type Fork struct {
+	Name    string
+	Epoch   Epoch
+	Version Version
+	Family  TypeFamily
+}
+
+var ErrForkOrder = errors.New("forks must be registered in order of increasing epoch")
+
+// Keeps track of the forks of a chain, ordered by epoch.
+type Registry struct {
+	forks []*Fork
+}
+
+// Creates a registry starting with the genesis fork.
+func NewRegistry(genesis *Fork) (*Registry, error) {
+	if genesis.Epoch != GENESIS_EPOCH {
+		return nil, fmt.Errorf("genesis fork %s must start at the genesis epoch, not %d", genesis.Name, genesis.Epoch)
+	}
+	return &Registry{forks: []*Fork{genesis}}, nil
+}
+
+// Adds a fork after the last registered fork.
+func (r *Registry) Register(fork *Fork) error {
+	last := r.forks[len(r.forks)-1]
+	if fork.Epoch <= last.Epoch {
+		return fmt.Errorf("fork %s at epoch %d is not after fork %s at epoch %d: %w",
+			fork.Name, fork.Epoch, last.Name, last.Epoch, ErrForkOrder)
+	}
+	r.forks = append(r.forks, fork)
+	return nil
+}
+
+// The fork that is active at the given epoch.
+func (r *Registry) ForkAt(epoch Epoch) *Fork {
+	for i := len(r.forks) - 1; i > 0; i-- {
+		if r.forks[i].Epoch <= epoch {
+			return r.forks[i]
+		}
+	}
+	return r.forks[0]
+}
+
+// The first fork after the given epoch, or nil if there is none.
+func (r *Registry) NextFork(epoch Epoch) *Fork {
+	for _, f := range r.forks {
+		if f.Epoch > epoch {
+			return f
+		}
+	}
+	return nil
+}
+
+// The signature domain of a message of the given epoch,
+// without a state, e.g. to verify messages from around a fork.
+func (r *Registry) ComputeDomain(dom BLSDomainType, messageEpoch Epoch) BLSDomain {
+	return ComputeDomain(dom, r.ForkAt(messageEpoch).Version)
+}
+
+// Decodes a state, with the types of the fork of the given epoch.
+func (r *Registry) DecodeState(epoch Epoch, rd io.Reader, length uint64) (State, error) {
+	return r.ForkAt(epoch).Family.DecodeState(rd, length)
+}
+
+// Decodes a signed block, with the types of the fork of the given epoch.
+func (r *Registry) DecodeBlock(epoch Epoch, rd io.Reader, length uint64) (Block, error) {
+	return r.ForkAt(epoch).Family.DecodeBlock(rd, length)
+}
+
+// The index of the fork with the given version, or -1 if it is not registered.
+func (r *Registry) indexOf(version Version) int {
+	for i, f := range r.forks {
+		if f.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// The fork the state is in, based on its current version, or nil if it is not registered.
+func (r *Registry) ForkOf(state State) *Fork {
+	if i := r.indexOf(state.CurrentVersion()); i >= 0 {
+		return r.forks[i]
+	}
+	return nil
+}
+
+// Process slots up to the given slot, upgrading the state at the start of every fork epoch that is reached.
+// The returned state may be of a different fork than the given state.
+func (r *Registry) ProcessSlots(state State, slot Slot) (State, error) {
+	i := r.indexOf(state.CurrentVersion())
+	if i < 0 {
+		return nil, fmt.Errorf("state has unknown fork version %x", state.CurrentVersion())
+	}
+	for ; i+1 < len(r.forks); i++ {
+		next := r.forks[i+1]
+		forkSlot := next.Epoch.GetStartSlot()
+		if forkSlot > slot {
+			break
+		}
+		// the epoch transition of the last epoch before the fork happens with the rules of the old fork.
+		state.ProcessSlots(forkSlot)
+		upgraded, err := next.Family.Upgrade(state, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade state to fork %s: %w", next.Name, err)
+		}
+		state = upgraded
+	}
+	state.ProcessSlots(slot)
+	return state, nil
+}
+
+// Transitions the state to the slot of the block, upgrading it when passing a fork, then processes the block.
+// The block must be of the fork that is active at its slot.
+// The returned state may be of a different fork than the given state.
+func (r *Registry) StateTransition(state State, block Block, validateResult bool) (State, error) {
+	state, err := r.ProcessSlots(state, block.Slot())
+	if err != nil {
+		return nil, err
+	}
+	if err := state.StateTransition(block, validateResult); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,136 @@
+package forks
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon/versioning"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zssz"
+	"io"
+)
+
+type Phase0State struct {
+	*phase0.FullFeaturedState
+}
+
+func (s Phase0State) StateTransition(block Block, validateResult bool) error {
+	b, ok := block.(Phase0Block)
+	if !ok {
+		return fmt.Errorf("expected phase0 block, got %T", block)
+	}
+	blockProc := &phase0.BlockProcessFeature{Block: b.SignedBeaconBlock, Meta: s.FullFeaturedState}
+	return s.FullFeaturedState.StateTransition(blockProc, validateResult)
+}
+
+func (s Phase0State) Encode(w io.Writer) error {
+	_, err := zssz.Encode(w, s.BeaconState, phase0.BeaconStateSSZ)
+	return err
+}
+
+type Phase0Block struct {
+	*phase0.SignedBeaconBlock
+}
+
+func (b Phase0Block) Slot() Slot {
+	return b.Message.Slot
+}
+
+func (b Phase0Block) Encode(w io.Writer) error {
+	_, err := zssz.Encode(w, b.SignedBeaconBlock, phase0.SignedBeaconBlockSSZ)
+	return err
+}
+
+// The phase0 types. Also usable for forks that only change the fork version.
+type Phase0Family struct{}
+
+func (Phase0Family) DecodeState(r io.Reader, length uint64) (State, error) {
+	state := new(phase0.BeaconState)
+	if err := zssz.Decode(r, length, state, phase0.BeaconStateSSZ); err != nil {
+		return nil, err
+	}
+	full := phase0.NewFullFeaturedState(state)
+	full.LoadPrecomputedData()
+	return Phase0State{full}, nil
+}
+
+func (Phase0Family) DecodeBlock(r io.Reader, length uint64) (Block, error) {
+	block := new(phase0.SignedBeaconBlock)
+	if err := zssz.Decode(r, length, block, phase0.SignedBeaconBlockSSZ); err != nil {
+		return nil, err
+	}
+	return Phase0Block{block}, nil
+}
+
+// Upgrades a phase0 state by changing the fork version. The state is copied, the pre-state is not modified.
+func (f Phase0Family) Upgrade(pre State, fork *Fork) (State, error) {
+	prev, ok := pre.(Phase0State)
+	if !ok {
+		return nil, fmt.Errorf("cannot upgrade %T to a phase0 state", pre)
+	}
+	var buf bytes.Buffer
+	if err := pre.Encode(&buf); err != nil {
+		return nil, err
+	}
+	post, err := f.DecodeState(&buf, uint64(buf.Len()))
+	if err != nil {
+		return nil, err
+	}
+	p := post.(Phase0State)
+	p.Concurrency = prev.Concurrency
+	p.Rewards = prev.Rewards
+	p.Observer = prev.Observer
+	p.Fork = versioning.Fork{
+		PreviousVersion: pre.CurrentVersion(),
+		CurrentVersion:  fork.Version,
+		Epoch:           fork.Epoch,
+	}
+	return p, nil
+}
+
+var Phase0 = &Fork{
+	Name:    "phase0",
+	Epoch:   GENESIS_EPOCH,
+	Version: Version{},
+	Family:  Phase0Family{},
+}
+
+func phase0Of(state State) (*phase0.FullFeaturedState, error) {
+	s, ok := state.(Phase0State)
+	if !ok {
+		return nil, fmt.Errorf("expected phase0 state, got %T", state)
+	}
+	return s.FullFeaturedState, nil
+}
+
+// Processes slots of a phase0 state, upgrading it at the start of every fork epoch that is reached.
+// This is the entry point for the packages that work with phase0 types, like chainsync, forkchoice, archive and sim.
+// A nil registry only has the phase0 fork. Returns an error if a fork with other types is reached.
+// The given state may be modified, continue with the returned state.
+func (r *Registry) ProcessPhase0Slots(state *phase0.FullFeaturedState, slot Slot) (*phase0.FullFeaturedState, error) {
+	if r == nil {
+		state.ProcessSlots(slot)
+		return state, nil
+	}
+	out, err := r.ProcessSlots(Phase0State{state}, slot)
+	if err != nil {
+		return nil, err
+	}
+	return phase0Of(out)
+}
+
+// Transitions a phase0 state to the slot of the block, upgrading it when passing a fork, then processes the block.
+// Like ProcessPhase0Slots, a nil registry only has the phase0 fork, and the returned state is the state to continue with.
+func (r *Registry) Phase0StateTransition(state *phase0.FullFeaturedState, block *phase0.SignedBeaconBlock, validateResult bool) (*phase0.FullFeaturedState, error) {
+	if r == nil {
+		if err := state.StateTransition(&phase0.BlockProcessFeature{Block: block, Meta: state}, validateResult); err != nil {
+			return nil, err
+		}
+		return state, nil
+	}
+	out, err := r.StateTransition(Phase0State{state}, Phase0Block{block}, validateResult)
+	if err != nil {
+		return nil, err
+	}
+	return phase0Of(out)
+}
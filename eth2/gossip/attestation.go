@@ -0,0 +1,101 @@
+package gossip
+
+import (
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	. "github.com/protolambda/zrnt/eth2/core"
+)
+
+// Validates an unaggregated attestation, received on the attestation topic of the given subnet.
+func (gv *GossipValidator) ValidateAttestation(subnet uint64, att *Attestation) (Result, error) {
+	data := &att.Data
+	if res, err := gv.checkAttestationSlot(data.Slot); err != nil {
+		return res, err
+	}
+	committee, res, err := gv.attestationCommittee(data)
+	if err != nil {
+		return res, err
+	}
+	committeesPerSlot := gv.Meta.GetCommitteeCountAtSlot(data.Slot)
+	if expected := ComputeSubnetForAttestation(committeesPerSlot, data.Slot, data.Index); expected != subnet {
+		return Reject, fmt.Errorf("attestation of committee %d at slot %d is for subnet %d, not %d", data.Index, data.Slot, expected, subnet)
+	}
+	if bitLen := att.AggregationBits.BitLen(); bitLen != uint64(len(committee)) {
+		return Reject, fmt.Errorf("attestation bits length %d does not match committee size %d", bitLen, len(committee))
+	}
+	participants := att.AggregationBits.FilterParticipants(append([]ValidatorIndex(nil), committee...))
+	if len(participants) != 1 {
+		return Reject, fmt.Errorf("unaggregated attestation must have exactly 1 participant, got %d", len(participants))
+	}
+	attester := participants[0]
+	if gv.Seen.seenSlot(gv.Seen.attestations, attester, data.Slot) {
+		return Ignore, fmt.Errorf("already seen attestation of validator %d at slot %d", attester, data.Slot)
+	}
+	indexed := &IndexedAttestation{
+		AttestingIndices: participants,
+		Data:             *data,
+		Signature:        att.Signature,
+	}
+	if err := indexed.Validate(gv.Meta); err != nil {
+		return Reject, err
+	}
+	if !gv.Seen.markSlot(gv.Seen.attestations, attester, data.Slot) {
+		return Ignore, fmt.Errorf("already seen attestation of validator %d at slot %d", attester, data.Slot)
+	}
+	return Accept, nil
+}
+
+// Validates an aggregate received on the beacon_aggregate_and_proof topic.
+func (gv *GossipValidator) ValidateAggregateAndProof(agg *AggregateAndProof) (Result, error) {
+	aggregatorIndex := agg.AggregatorIndex
+	data := &agg.Aggregate.Data
+	if res, err := gv.checkAttestationSlot(data.Slot); err != nil {
+		return res, err
+	}
+	if gv.Seen.seenSlot(gv.Seen.aggregates, aggregatorIndex, data.Slot) {
+		return Ignore, fmt.Errorf("already seen aggregate of validator %d at slot %d", aggregatorIndex, data.Slot)
+	}
+	committee, res, err := gv.attestationCommittee(data)
+	if err != nil {
+		return res, err
+	}
+	inCommittee := false
+	for _, i := range committee {
+		if i == aggregatorIndex {
+			inCommittee = true
+			break
+		}
+	}
+	if !inCommittee {
+		return Reject, fmt.Errorf("aggregator %d is not in committee %d at slot %d", aggregatorIndex, data.Index, data.Slot)
+	}
+	if !IsAggregator(uint64(len(committee)), agg.SelectionProof) {
+		return Reject, fmt.Errorf("validator %d is not selected as aggregator", aggregatorIndex)
+	}
+	if !gv.Meta.VerifySelectionProof(aggregatorIndex, data.Slot, agg.SelectionProof) {
+		return Reject, fmt.Errorf("invalid selection proof of aggregator %d", aggregatorIndex)
+	}
+	indexed, err := agg.Aggregate.ConvertToIndexed(committee)
+	if err != nil {
+		return Reject, err
+	}
+	if err := indexed.Validate(gv.Meta); err != nil {
+		return Reject, err
+	}
+	if !gv.Seen.markSlot(gv.Seen.aggregates, aggregatorIndex, data.Slot) {
+		return Ignore, fmt.Errorf("already seen aggregate of validator %d at slot %d", aggregatorIndex, data.Slot)
+	}
+	return Accept, nil
+}
+
+// Validates a signed aggregate, checking the aggregator signature before the aggregate itself.
+func (gv *GossipValidator) ValidateSignedAggregateAndProof(signed *SignedAggregateAndProof) (Result, error) {
+	index := signed.Message.AggregatorIndex
+	if !gv.Meta.IsValidIndex(index) {
+		return Reject, fmt.Errorf("aggregator index %d out of range", index)
+	}
+	if !gv.Meta.VerifyAggregateAndProofSignature(signed) {
+		return Reject, fmt.Errorf("invalid aggregate and proof signature of aggregator %d", index)
+	}
+	return gv.ValidateAggregateAndProof(&signed.Message)
+}
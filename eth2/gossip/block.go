@@ -0,0 +1,43 @@
+package gossip
+
+import (
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+)
+
+// Validates a block received on the beacon_block topic.
+func (gv *GossipValidator) ValidateBlock(block *phase0.SignedBeaconBlock) (Result, error) {
+	slot := block.Message.Slot
+	_, latest, ok := gv.currentSlotRange()
+	if !ok {
+		return Ignore, fmt.Errorf("block slot %d is before genesis", slot)
+	}
+	// Future blocks may be queued by the client, but are not propagated yet.
+	if slot > latest {
+		return Ignore, fmt.Errorf("block slot %d is in the future, current slot %d", slot, latest)
+	}
+	if finalizedSlot := gv.Meta.Finalized().Epoch.GetStartSlot(); slot <= finalizedSlot {
+		return Ignore, fmt.Errorf("block slot %d is not after finalized slot %d", slot, finalizedSlot)
+	}
+	if slot.ToEpoch() != gv.Meta.CurrentEpoch() {
+		return Ignore, fmt.Errorf("no proposers available for block slot %d", slot)
+	}
+	proposer := gv.Meta.GetBeaconProposerIndex(slot)
+	if gv.Seen.seenSlot(gv.Seen.blocks, proposer, slot) {
+		return Ignore, fmt.Errorf("already seen block of proposer %d at slot %d", proposer, slot)
+	}
+	if !bls.BlsVerify(
+		gv.Meta.Pubkey(proposer),
+		ssz.HashTreeRoot(&block.Message, phase0.BeaconBlockSSZ),
+		block.Signature,
+		gv.Meta.GetDomain(DOMAIN_BEACON_PROPOSER, slot.ToEpoch())) {
+		return Reject, fmt.Errorf("block has invalid signature for proposer %d", proposer)
+	}
+	if !gv.Seen.markSlot(gv.Seen.blocks, proposer, slot) {
+		return Ignore, fmt.Errorf("already seen block of proposer %d at slot %d", proposer, slot)
+	}
+	return Accept, nil
+}
@@ -0,0 +1,125 @@
+package gossip
+
+import (
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon/attestations"
+	"github.com/protolambda/zrnt/eth2/beacon/exits"
+	"github.com/protolambda/zrnt/eth2/beacon/slashings/attslash"
+	"github.com/protolambda/zrnt/eth2/beacon/slashings/propslash"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
+	"time"
+)
+
+// Network configuration, fixed for phase 0.
+const MAXIMUM_GOSSIP_CLOCK_DISPARITY = 500 * time.Millisecond
+const ATTESTATION_PROPAGATION_SLOT_RANGE Slot = 32
+const ATTESTATION_SUBNET_COUNT = 64
+
+// The outcome of validating a gossip message.
+type Result uint8
+
+const (
+	// Valid, the message is propagated.
+	Accept Result = iota
+	// Not propagated, but the sender is not at fault, e.g. duplicates and messages outside of the slot window.
+	Ignore
+	// Invalid, not propagated, and the sender may be penalized.
+	Reject
+)
+
+func (r Result) String() string {
+	switch r {
+	case Accept:
+		return "accept"
+	case Ignore:
+		return "ignore"
+	case Reject:
+		return "reject"
+	default:
+		return fmt.Sprintf("result %d", uint8(r))
+	}
+}
+
+type GossipMeta interface {
+	meta.Versioning
+	meta.Genesis
+	meta.Finality
+	meta.Proposers
+	meta.BeaconCommittees
+	meta.CommitteeCount
+	meta.RegistrySize
+	meta.Pubkeys
+	exits.VoluntaryExitProcessor
+	propslash.ProposerSlashingProcessor
+	attslash.AttesterSlashingProcessor
+	VerifySelectionProof(index ValidatorIndex, slot Slot, selectionProof BLSSignature) bool
+	VerifyAggregateAndProofSignature(signed *attestations.SignedAggregateAndProof) bool
+}
+
+// Validates gossip messages against a state, the way a phase 0 client would before propagating them.
+// The state is expected to be processed up to the current slot: proposers and committees
+// are only available for the epochs around the state, messages outside of that range are ignored.
+type GossipValidator struct {
+	Meta GossipMeta
+	// Messages that were seen before, and are not propagated again.
+	Seen *SeenCache
+	// The wall-clock time, to check slot windows with.
+	Now func() time.Time
+}
+
+func NewGossipValidator(m GossipMeta) *GossipValidator {
+	return &GossipValidator{Meta: m, Seen: NewSeenCache(), Now: time.Now}
+}
+
+// The range of slots the wall-clock may be in, given the maximum clock disparity.
+// Returns false if the clock is before genesis.
+func (gv *GossipValidator) currentSlotRange() (earliest Slot, latest Slot, ok bool) {
+	genesis := time.Unix(int64(gv.Meta.GetGenesisTime()), 0)
+	now := gv.Now()
+	slotDuration := time.Duration(SECONDS_PER_SLOT) * time.Second
+	late := now.Add(MAXIMUM_GOSSIP_CLOCK_DISPARITY).Sub(genesis)
+	if late < 0 {
+		return 0, 0, false
+	}
+	latest = Slot(late / slotDuration)
+	if early := now.Add(-MAXIMUM_GOSSIP_CLOCK_DISPARITY).Sub(genesis); early > 0 {
+		earliest = Slot(early / slotDuration)
+	}
+	return earliest, latest, true
+}
+
+// Checks if there is shuffling data for the epoch, to retrieve committees with.
+func (gv *GossipValidator) hasCommittees(epoch Epoch) bool {
+	current := gv.Meta.CurrentEpoch()
+	return gv.Meta.PreviousEpoch() <= epoch && epoch <= current+1
+}
+
+// Checks the slot of an attestation to be within the propagation range.
+func (gv *GossipValidator) checkAttestationSlot(slot Slot) (Result, error) {
+	earliest, latest, ok := gv.currentSlotRange()
+	if !ok {
+		return Ignore, fmt.Errorf("attestation slot %d is before genesis", slot)
+	}
+	if slot > latest {
+		return Ignore, fmt.Errorf("attestation slot %d is in the future, current slot %d", slot, latest)
+	}
+	if slot+ATTESTATION_PROPAGATION_SLOT_RANGE < earliest {
+		return Ignore, fmt.Errorf("attestation slot %d is too old, current slot %d", slot, earliest)
+	}
+	return Accept, nil
+}
+
+// Retrieves the committee of an attestation, checking if it is known.
+func (gv *GossipValidator) attestationCommittee(data *attestations.AttestationData) ([]ValidatorIndex, Result, error) {
+	if data.Target.Epoch != data.Slot.ToEpoch() {
+		return nil, Reject, fmt.Errorf("attestation target epoch %d does not match slot %d", data.Target.Epoch, data.Slot)
+	}
+	if !gv.hasCommittees(data.Target.Epoch) {
+		return nil, Ignore, fmt.Errorf("no committees available for epoch %d", data.Target.Epoch)
+	}
+	if uint64(data.Index) >= gv.Meta.GetCommitteeCountAtSlot(data.Slot) {
+		return nil, Reject, fmt.Errorf("committee index %d out of range", data.Index)
+	}
+	return gv.Meta.GetBeaconCommittee(data.Slot, data.Index), Accept, nil
+}
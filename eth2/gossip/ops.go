@@ -0,0 +1,86 @@
+package gossip
+
+import (
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/exits"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/attslash"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/propslash"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"sort"
+)
+
+// Validates a voluntary exit received on the voluntary_exit topic.
+func (gv *GossipValidator) ValidateVoluntaryExit(exit *SignedVoluntaryExit) (Result, error) {
+	index := exit.Message.ValidatorIndex
+	if gv.Seen.seenIndex(gv.Seen.exits, index) {
+		return Ignore, fmt.Errorf("already seen exit of validator %d", index)
+	}
+	if err := gv.Meta.ValidateVoluntaryExit(exit); err != nil {
+		return Reject, err
+	}
+	if !gv.Seen.markIndex(gv.Seen.exits, index) {
+		return Ignore, fmt.Errorf("already seen exit of validator %d", index)
+	}
+	return Accept, nil
+}
+
+// Validates a proposer slashing received on the proposer_slashing topic.
+func (gv *GossipValidator) ValidateProposerSlashing(ps *ProposerSlashing) (Result, error) {
+	index := ps.ProposerIndex
+	if gv.Seen.seenIndex(gv.Seen.proposerSlashings, index) {
+		return Ignore, fmt.Errorf("already seen slashing of proposer %d", index)
+	}
+	if err := gv.Meta.ValidateProposerSlashing(ps); err != nil {
+		return Reject, err
+	}
+	if !gv.Seen.markIndex(gv.Seen.proposerSlashings, index) {
+		return Ignore, fmt.Errorf("already seen slashing of proposer %d", index)
+	}
+	return Accept, nil
+}
+
+// Checks that the attesting indices are sorted and unique, like the indexed attestation validation.
+// The intersection of the attesting indices is only meaningful if they are.
+func checkAttestingIndices(indices ValidatorSet) error {
+	if !sort.IsSorted(indices) {
+		return IndexedAttestationUnsorted
+	}
+	for i := 1; i < len(indices); i++ {
+		if indices[i-1] == indices[i] {
+			return fmt.Errorf("%w: at %d and %d, both: %d", IndexedAttestationDuplicateIndex, i-1, i, indices[i])
+		}
+	}
+	return nil
+}
+
+// Validates an attester slashing received on the attester_slashing topic.
+// Propagated only if it slashes at least one validator that was not slashed by an earlier attester slashing.
+func (gv *GossipValidator) ValidateAttesterSlashing(as *AttesterSlashing) (Result, error) {
+	if err := checkAttestingIndices(ValidatorSet(as.Attestation1.AttestingIndices)); err != nil {
+		return Reject, fmt.Errorf("attestation 1: %w", err)
+	}
+	if err := checkAttestingIndices(ValidatorSet(as.Attestation2.AttestingIndices)); err != nil {
+		return Reject, fmt.Errorf("attestation 2: %w", err)
+	}
+	var indices []ValidatorIndex
+	ValidatorSet(as.Attestation1.AttestingIndices).ZigZagJoin(ValidatorSet(as.Attestation2.AttestingIndices), func(i ValidatorIndex) {
+		indices = append(indices, i)
+	}, nil)
+	unseen := false
+	for _, i := range indices {
+		if !gv.Seen.seenIndex(gv.Seen.attesterSlashings, i) {
+			unseen = true
+			break
+		}
+	}
+	if !unseen {
+		return Ignore, fmt.Errorf("already seen slashings of all %d validators", len(indices))
+	}
+	if err := gv.Meta.ValidateAttesterSlashing(as); err != nil {
+		return Reject, err
+	}
+	for _, i := range indices {
+		gv.Seen.markIndex(gv.Seen.attesterSlashings, i)
+	}
+	return Accept, nil
+}
@@ -0,0 +1,88 @@
+package gossip
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"sync"
+)
+
+type validatorSlot struct {
+	Index ValidatorIndex
+	Slot  Slot
+}
+
+// Tracks the first valid message per validator for each topic. Safe for concurrent use.
+type SeenCache struct {
+	mu sync.Mutex
+	// proposer of block, per slot
+	blocks map[validatorSlot]struct{}
+	// aggregator of aggregate, per slot
+	aggregates map[validatorSlot]struct{}
+	// attester of unaggregated attestation, per slot
+	attestations map[validatorSlot]struct{}
+	// validators that exited, slashed proposers, and validators slashed by attester slashings
+	exits             map[ValidatorIndex]struct{}
+	proposerSlashings map[ValidatorIndex]struct{}
+	attesterSlashings map[ValidatorIndex]struct{}
+}
+
+func NewSeenCache() *SeenCache {
+	return &SeenCache{
+		blocks:            make(map[validatorSlot]struct{}),
+		aggregates:        make(map[validatorSlot]struct{}),
+		attestations:      make(map[validatorSlot]struct{}),
+		exits:             make(map[ValidatorIndex]struct{}),
+		proposerSlashings: make(map[ValidatorIndex]struct{}),
+		attesterSlashings: make(map[ValidatorIndex]struct{}),
+	}
+}
+
+func (c *SeenCache) seenSlot(m map[validatorSlot]struct{}, index ValidatorIndex, slot Slot) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := m[validatorSlot{index, slot}]
+	return ok
+}
+
+// Marks the entry as seen, returns false if it was already seen.
+func (c *SeenCache) markSlot(m map[validatorSlot]struct{}, index ValidatorIndex, slot Slot) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := validatorSlot{index, slot}
+	if _, ok := m[k]; ok {
+		return false
+	}
+	m[k] = struct{}{}
+	return true
+}
+
+func (c *SeenCache) seenIndex(m map[ValidatorIndex]struct{}, index ValidatorIndex) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := m[index]
+	return ok
+}
+
+// Marks the entry as seen, returns false if it was already seen.
+func (c *SeenCache) markIndex(m map[ValidatorIndex]struct{}, index ValidatorIndex) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := m[index]; ok {
+		return false
+	}
+	m[index] = struct{}{}
+	return true
+}
+
+// Removes the per-slot entries older than the given slot.
+// Messages of these slots are outside of the propagation range, and ignored regardless.
+func (c *SeenCache) Prune(minSlot Slot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range []map[validatorSlot]struct{}{c.blocks, c.aggregates, c.attestations} {
+		for k := range m {
+			if k.Slot < minSlot {
+				delete(m, k)
+			}
+		}
+	}
+}
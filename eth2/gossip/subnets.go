@@ -0,0 +1,114 @@
+package gossip
+
+import (
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
+	"sort"
+)
+
+// The subnet that attestations of the committee at the slot and committee index are propagated on.
+// Committees are numbered from the start of the epoch, to spread them over all subnets.
+func ComputeSubnetForAttestation(committeesPerSlot uint64, slot Slot, index CommitteeIndex) uint64 {
+	slotsSinceEpochStart := uint64(slot % SLOTS_PER_EPOCH)
+	committeesSinceEpochStart := committeesPerSlot * slotsSinceEpochStart
+	return (committeesSinceEpochStart + uint64(index)) % ATTESTATION_SUBNET_COUNT
+}
+
+// The attestation duty of a validator, and the subnet to publish the attestation on.
+type SubnetDuty struct {
+	Validator      ValidatorIndex
+	Slot           Slot
+	CommitteeIndex CommitteeIndex
+	Subnet         uint64
+}
+
+// The subnets a set of validators must join during an epoch.
+type SubnetPlan struct {
+	Epoch Epoch
+	// Ordered by slot, then committee index
+	Duties []SubnetDuty
+}
+
+// The distinct subnets of the duties, sorted.
+func (p *SubnetPlan) Subnets() []uint64 {
+	seen := make(map[uint64]struct{})
+	out := make([]uint64, 0)
+	for _, d := range p.Duties {
+		if _, ok := seen[d.Subnet]; !ok {
+			seen[d.Subnet] = struct{}{}
+			out = append(out, d.Subnet)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// The validators per subnet, for the slot.
+func (p *SubnetPlan) SlotSubnets(slot Slot) map[uint64][]ValidatorIndex {
+	out := make(map[uint64][]ValidatorIndex)
+	for _, d := range p.Duties {
+		if d.Slot == slot {
+			out[d.Subnet] = append(out[d.Subnet], d.Validator)
+		}
+	}
+	return out
+}
+
+type SubnetPlanner struct {
+	Meta interface {
+		meta.Versioning
+		meta.CommitteeCount
+		meta.BeaconCommittees
+	}
+}
+
+// Plans the subnets the validators must join for the current and next epoch,
+// the epochs that committees are known for ahead of time.
+func (p *SubnetPlanner) PlanSubnets(validators []ValidatorIndex) ([]SubnetPlan, error) {
+	current := p.Meta.CurrentEpoch()
+	out := make([]SubnetPlan, 0, 2)
+	for _, epoch := range []Epoch{current, current + 1} {
+		plan, err := p.PlanEpoch(epoch, validators)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, plan)
+	}
+	return out, nil
+}
+
+// Plans the subnets the validators must join for the epoch.
+// Returns an error if the epoch is not the previous, current or next epoch of the state,
+// the committees of other epochs are not known.
+func (p *SubnetPlanner) PlanEpoch(epoch Epoch, validators []ValidatorIndex) (SubnetPlan, error) {
+	if current := p.Meta.CurrentEpoch(); epoch < p.Meta.PreviousEpoch() || epoch > current+1 {
+		return SubnetPlan{}, fmt.Errorf("cannot plan subnets of epoch %d, committees are only known for epochs %d to %d",
+			epoch, p.Meta.PreviousEpoch(), current+1)
+	}
+	set := make(map[ValidatorIndex]struct{}, len(validators))
+	for _, v := range validators {
+		set[v] = struct{}{}
+	}
+	plan := SubnetPlan{Epoch: epoch}
+	start := epoch.GetStartSlot()
+	for slot := start; slot < start+SLOTS_PER_EPOCH; slot++ {
+		committeesPerSlot := p.Meta.GetCommitteeCountAtSlot(slot)
+		for index := CommitteeIndex(0); index < CommitteeIndex(committeesPerSlot); index++ {
+			subnet := ComputeSubnetForAttestation(committeesPerSlot, slot, index)
+			for _, v := range p.Meta.GetBeaconCommittee(slot, index) {
+				if _, ok := set[v]; ok {
+					plan.Duties = append(plan.Duties, SubnetDuty{
+						Validator:      v,
+						Slot:           slot,
+						CommitteeIndex: index,
+						Subnet:         subnet,
+					})
+				}
+			}
+		}
+	}
+	return plan, nil
+}
@@ -23,6 +23,11 @@ type AttestationDeltas interface {
 	AttestationDeltas() *Deltas
 }
 
+type RewardsReporting interface {
+	// The report to account rewards and penalties in, per component. Nil if not reporting.
+	RewardsReport() *RewardsReport
+}
+
 type RegistrySize interface {
 	IsValidIndex(index ValidatorIndex) bool
 	ValidatorCount() uint64
@@ -39,7 +44,12 @@ type EffectiveBalances interface {
 }
 
 type EffectiveBalancesUpdate interface {
-	UpdateEffectiveBalances()
+	UpdateEffectiveBalances(workers int)
+}
+
+type Concurrency interface {
+	// The amount of workers to split per-validator epoch processing over. Sequential if 1 or less.
+	GetConcurrency() int
 }
 
 type Finality interface {
@@ -102,6 +112,10 @@ type Versioning interface {
 	GetDomain(dom BLSDomainType, messageEpoch Epoch) BLSDomain
 }
 
+type Genesis interface {
+	GetGenesisTime() Timestamp
+}
+
 type Eth1Voting interface {
 	ResetEth1Votes()
 }
@@ -154,8 +168,13 @@ type ActivationExit interface {
 	ExitQueueEnd(epoch Epoch) Epoch
 }
 
+type ExitTracking interface {
+	// Tracks the exit epoch of a validator that initiated an exit, to keep the exit queue end up to date.
+	TrackExit(exitEpoch Epoch)
+}
+
 type ActivationQeueue interface {
-	ProcessActivationQueue(activationEpoch Epoch, currentEpoch Epoch)
+	ProcessActivationQueue(activationEpoch Epoch, currentEpoch Epoch) (activated []ValidatorIndex)
 }
 
 type ActiveValidatorCount interface {
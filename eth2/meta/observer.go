@@ -0,0 +1,45 @@
+package meta
+
+import . "github.com/protolambda/zrnt/eth2/core"
+
+// Receives events during state transitions, e.g. to monitor a chain without diffing states.
+// Events are emitted after the change is applied to the state. Observers must not modify the state.
+type Observer interface {
+	// The slot was processed, including the epoch transition if it was the last slot of the epoch.
+	SlotProcessed(slot Slot)
+	// The state entered the given epoch, after the epoch transition.
+	EpochStarted(epoch Epoch)
+	// The validator was dequeued for activation, and will be active at the given epoch.
+	ValidatorActivated(index ValidatorIndex, activationEpoch Epoch)
+	// The validator was put in the exit queue, and will be exited at the given epoch.
+	ExitInitiated(index ValidatorIndex, exitEpoch Epoch)
+	// The validator was slashed, rewarding the whistleblower.
+	ValidatorSlashed(index ValidatorIndex, whistleblower ValidatorIndex)
+	// The current justified checkpoint changed.
+	JustificationChanged(previous Checkpoint, current Checkpoint)
+	// The finalized checkpoint changed.
+	FinalizationChanged(previous Checkpoint, current Checkpoint)
+	// The deposit was applied, either creating a new validator or topping up an existing one.
+	DepositApplied(depositIndex DepositIndex, validatorIndex ValidatorIndex, amount Gwei, newValidator bool)
+	// The eth1 data was replaced by the majority vote.
+	Eth1DataUpdated(depositRoot Root, depositCount DepositIndex, blockHash Root)
+}
+
+type Observing interface {
+	// The observer to emit events to. Never nil.
+	GetObserver() Observer
+}
+
+// Observer that ignores all events.
+type NoopObserver struct{}
+
+func (NoopObserver) SlotProcessed(slot Slot)                                             {}
+func (NoopObserver) EpochStarted(epoch Epoch)                                            {}
+func (NoopObserver) ValidatorActivated(index ValidatorIndex, activationEpoch Epoch)      {}
+func (NoopObserver) ExitInitiated(index ValidatorIndex, exitEpoch Epoch)                 {}
+func (NoopObserver) ValidatorSlashed(index ValidatorIndex, whistleblower ValidatorIndex) {}
+func (NoopObserver) JustificationChanged(previous Checkpoint, current Checkpoint)        {}
+func (NoopObserver) FinalizationChanged(previous Checkpoint, current Checkpoint)         {}
+func (NoopObserver) DepositApplied(depositIndex DepositIndex, validatorIndex ValidatorIndex, amount Gwei, newValidator bool) {
+}
+func (NoopObserver) Eth1DataUpdated(depositRoot Root, depositCount DepositIndex, blockHash Root) {}
@@ -0,0 +1,34 @@
+package phase0
+
+import (
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon/header"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+)
+
+// Boots a state from a trusted finalized checkpoint, instead of from genesis.
+// The state must be the post-state of the checkpoint block, optionally processed up to the start of the checkpoint epoch.
+// The block and the latest block header of the state must match the checkpoint root.
+// The state is processed up to the start of the checkpoint epoch, like the checkpoint states of the fork choice.
+// The given state is modified, not copied.
+func CheckpointState(state *BeaconState, block *BeaconBlock, cp Checkpoint) (*FullFeaturedState, error) {
+	if root := ssz.HashTreeRoot(block, BeaconBlockSSZ); root != cp.Root {
+		return nil, fmt.Errorf("block root %x does not match checkpoint root %x", root, cp.Root)
+	}
+	latestHeader := state.LatestBlockHeader
+	// The state root is only filled in when the next slot is processed
+	if latestHeader.StateRoot == (Root{}) {
+		latestHeader.StateRoot = ssz.HashTreeRoot(state, BeaconStateSSZ)
+	}
+	if root := ssz.HashTreeRoot(&latestHeader, header.BeaconBlockHeaderSSZ); root != cp.Root {
+		return nil, fmt.Errorf("latest block header root %x does not match checkpoint root %x", root, cp.Root)
+	}
+	if epochStart := cp.Epoch.GetStartSlot(); state.Slot > epochStart {
+		return nil, fmt.Errorf("state at slot %d is past the start of checkpoint epoch %d", state.Slot, cp.Epoch)
+	}
+	full := NewFullFeaturedState(state)
+	full.LoadPrecomputedData()
+	full.ProcessSlots(cp.Epoch.GetStartSlot())
+	return full, nil
+}
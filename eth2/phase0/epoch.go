@@ -6,6 +6,7 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/registry"
 	"github.com/protolambda/zrnt/eth2/beacon/rewardpenalty"
 	"github.com/protolambda/zrnt/eth2/beacon/slashings"
+	"github.com/protolambda/zrnt/eth2/meta"
 )
 
 type EpochProcessFeature struct {
@@ -15,11 +16,20 @@ type EpochProcessFeature struct {
 		registry.RegistryUpdateEpochProcess
 		slashings.SlashingsEpochProcess
 		finalupdates.FinalUpdatesEpochProcess
+		meta.Finality
+		meta.Observing
 	}
 }
 
 func (f *EpochProcessFeature) ProcessEpoch() {
+	prevJustified, prevFinalized := f.Meta.CurrentJustified(), f.Meta.Finalized()
 	f.Meta.ProcessEpochJustification()
+	if justified := f.Meta.CurrentJustified(); justified != prevJustified {
+		f.Meta.GetObserver().JustificationChanged(prevJustified, justified)
+	}
+	if finalized := f.Meta.Finalized(); finalized != prevFinalized {
+		f.Meta.GetObserver().FinalizationChanged(prevFinalized, finalized)
+	}
 	f.Meta.ProcessEpochRewardsAndPenalties()
 	f.Meta.ProcessEpochRegistryUpdates()
 	f.Meta.ProcessEpochSlashings()
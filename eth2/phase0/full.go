@@ -1,8 +1,11 @@
 package phase0
 
 import (
+	"bytes"
+	"errors"
 	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
 	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	. "github.com/protolambda/zrnt/eth2/beacon/eth1"
 	. "github.com/protolambda/zrnt/eth2/beacon/exits"
 	. "github.com/protolambda/zrnt/eth2/beacon/finality"
 	. "github.com/protolambda/zrnt/eth2/beacon/finalupdates"
@@ -17,10 +20,19 @@ import (
 	. "github.com/protolambda/zrnt/eth2/beacon/slashings/attslash"
 	. "github.com/protolambda/zrnt/eth2/beacon/slashings/propslash"
 	. "github.com/protolambda/zrnt/eth2/beacon/transition"
+	. "github.com/protolambda/zrnt/eth2/beacon/weaksubjectivity"
 	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zssz"
 )
 
-// Full feature set for phase 0
+// Returned when a block is looked up by root, but not known, e.g. by a block source.
+var ErrUnknownBlock = errors.New("unknown block")
+
+// Full feature set for phase 0.
+// The slot and block transitions of the state follow the phase0 rules, and do not upgrade the state at fork epochs:
+// to process a chain with forks, transition the state with a forks.Registry.
 type FullFeaturedState struct {
 	// All base features a state has
 	*BeaconState
@@ -33,6 +45,9 @@ type FullFeaturedState struct {
 	ProposingFeature
 	*ProposersData
 
+	// Cached exit queue data, shadows the churn limit and exit queue end computation of the registry
+	*ExitQueueData
+
 	// Rewarding process, optimized to use precomputed crosslink/shuffling/etc. data
 	AttestationDeltasFeature // rewards/penalties computation for attestations
 
@@ -41,6 +56,7 @@ type FullFeaturedState struct {
 	JustificationFeature
 	RewardsAndPenaltiesFeature
 	RegistryUpdatesFeature
+	ExitFeature // emits exit events, shadows the registry state exit initiation
 	SlashingFeature
 	FinalUpdateFeature
 
@@ -48,7 +64,9 @@ type FullFeaturedState struct {
 	BlockHeaderFeature
 
 	// Process block operations
+	Eth1VotingFeature // emits eth1 data events, shadows the eth1 state vote processing
 	AttestationFeature
+	AggregationFeature
 	AttestSlashFeature
 	PropSlashFeature
 	DepositFeature
@@ -57,12 +75,56 @@ type FullFeaturedState struct {
 	SlotProcessFeature
 	EpochProcessFeature
 	TransitionFeature
+
+	WeakSubjectivityFeature
+
+	// Opt-in: the amount of workers to split per-validator epoch processing over.
+	// Zero or one for sequential processing (default).
+	Concurrency int
+
+	// Opt-in: a report to account the rewards and penalties in, per component. Nil to not report (default).
+	Rewards *RewardsReport
+
+	// Opt-in: observer of transition events. Nil to not emit events (default).
+	Observer meta.Observer
+}
+
+// Copies the state, with the same opt-in settings. The pre-computed data is loaded for the copy.
+// The rewards report is deep-copied, if any. The copy has no observer: events of the copy are not
+// mixed with those of the original. Set the observer of the copy explicitly if needed.
+func (f *FullFeaturedState) Copy() (*FullFeaturedState, error) {
+	var buf bytes.Buffer
+	if _, err := zssz.Encode(&buf, f.BeaconState, BeaconStateSSZ); err != nil {
+		return nil, err
+	}
+	state := new(BeaconState)
+	if err := zssz.Decode(&buf, uint64(buf.Len()), state, BeaconStateSSZ); err != nil {
+		return nil, err
+	}
+	out := NewFullFeaturedState(state)
+	out.LoadPrecomputedData()
+	out.Concurrency = f.Concurrency
+	if f.Rewards != nil {
+		out.Rewards = f.Rewards.Copy()
+	}
+	return out, nil
+}
+
+// The root of the latest block applied to the state.
+func (f *FullFeaturedState) HeadRoot() Root {
+	h := f.LatestBlockHeader
+	// The state root is only filled in when the next slot is processed.
+	if h.StateRoot == (Root{}) {
+		h.StateRoot = f.StateRoot()
+	}
+	return ssz.HashTreeRoot(&h, BeaconBlockHeaderSSZ)
 }
 
 func (f *FullFeaturedState) LoadPrecomputedData() {
 	// TODO: could re-use some pre-computed data from older states, worth benchmarking
 	f.ShufflingStatus = f.ShufflingFeature.LoadShufflingStatus()
 	f.ProposersData = f.LoadBeaconProposersData()
+	f.ExitQueueData = f.LoadExitQueueData(f.CurrentEpoch())
 }
 
 func (f *FullFeaturedState) RotateEpochData() {
@@ -74,6 +136,21 @@ func (f *FullFeaturedState) StartEpoch() {
 	f.RotateEpochData()
 }
 
+func (f *FullFeaturedState) GetConcurrency() int {
+	return f.Concurrency
+}
+
+func (f *FullFeaturedState) RewardsReport() *RewardsReport {
+	return f.Rewards
+}
+
+func (f *FullFeaturedState) GetObserver() meta.Observer {
+	if f.Observer == nil {
+		return meta.NoopObserver{}
+	}
+	return f.Observer
+}
+
 func (f *FullFeaturedState) CurrentProposer() BLSPubkey {
 	return f.Pubkey(f.GetBeaconProposerIndex(f.CurrentSlot()))
 }
@@ -103,6 +180,8 @@ func NewFullFeaturedState(state *BeaconState) *FullFeaturedState {
 	f.RewardsAndPenaltiesFeature.Meta = f
 	f.RegistryUpdatesFeature.Meta = f
 	f.RegistryUpdatesFeature.State = &f.RegistryState
+	f.ExitFeature.Meta = f
+	f.ExitFeature.State = &f.RegistryState
 	f.SlashingFeature.Meta = f
 	f.SlashingFeature.State = &f.SlashingsState
 	f.FinalUpdateFeature.Meta = f
@@ -113,8 +192,11 @@ func NewFullFeaturedState(state *BeaconState) *FullFeaturedState {
 	f.BlockHeaderFeature.Meta = f
 	f.BlockHeaderFeature.State = &f.BlockHeaderState
 
+	f.Eth1VotingFeature.Meta = f
+	f.Eth1VotingFeature.State = &f.Eth1State
 	f.AttestationFeature.Meta = f
 	f.AttestationFeature.State = &f.AttestationsState
+	f.AggregationFeature.Meta = f
 	f.AttestSlashFeature.Meta = f
 	f.PropSlashFeature.Meta = f
 	f.DepositFeature.Meta = f
@@ -124,5 +206,7 @@ func NewFullFeaturedState(state *BeaconState) *FullFeaturedState {
 	f.EpochProcessFeature.Meta = f
 	f.TransitionFeature.Meta = f
 
+	f.WeakSubjectivityFeature.Meta = f
+
 	return f
 }
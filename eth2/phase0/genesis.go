@@ -7,6 +7,7 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/header"
 	. "github.com/protolambda/zrnt/eth2/beacon/versioning"
 	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
 	"github.com/protolambda/zrnt/eth2/util/ssz"
 	"github.com/protolambda/zssz"
 )
@@ -19,16 +20,31 @@ func (_ *DepositRoots) Limit() uint64 {
 
 var DepositRootsSSZ = zssz.GetSSZ((*DepositRoots)(nil))
 
-func GenesisFromEth1(eth1BlockHash Root, time Timestamp, deps []Deposit, verifyDeposits bool) (*FullFeaturedState, error) {
+// Deposits are processed before the full featured state exists, without any observer.
+type genesisDepositsMeta struct {
+	*BeaconState
+}
+
+func (m *genesisDepositsMeta) GetObserver() meta.Observer {
+	return meta.NoopObserver{}
+}
+
+// The genesis time for a genesis triggered by an eth1 block with the given timestamp.
+func ComputeGenesisTime(eth1Timestamp Timestamp) Timestamp {
+	return eth1Timestamp - (eth1Timestamp % SECONDS_PER_DAY) + (2 * SECONDS_PER_DAY)
+}
+
+// Creates the state to process the genesis deposits into, before InitState can complete the genesis state.
+func PreGenesisState(eth1BlockHash Root, time Timestamp, depositCount DepositIndex) *BeaconState {
 	state := &BeaconState{
 		VersioningState: VersioningState{
-			GenesisTime: time - (time % SECONDS_PER_DAY) + (2 * SECONDS_PER_DAY),
+			GenesisTime: ComputeGenesisTime(time),
 		},
 		// Ethereum 1.0 chain data
 		Eth1State: Eth1State{
 			Eth1Data: Eth1Data{
 				DepositRoot:  Root{}, // incrementally overwritten during deposit processing
-				DepositCount: DepositIndex(len(deps)),
+				DepositCount: depositCount,
 				BlockHash:    eth1BlockHash,
 			},
 		},
@@ -40,8 +56,18 @@ func GenesisFromEth1(eth1BlockHash Root, time Timestamp, deps []Deposit, verifyD
 	}
 	// Seed RANDAO with Eth1 entropy
 	state.SeedRandao(eth1BlockHash)
+	return state
+}
+
+// Processes deposits into a pre-genesis state, without any observer.
+func GenesisDepositProcessor(state *BeaconState) *DepositFeature {
+	return &DepositFeature{Meta: &genesisDepositsMeta{state}}
+}
+
+func GenesisFromEth1(eth1BlockHash Root, time Timestamp, deps []Deposit, verifyDeposits bool) (*FullFeaturedState, error) {
+	state := PreGenesisState(eth1BlockHash, time, DepositIndex(len(deps)))
 
-	depProcessor := &DepositFeature{Meta: state}
+	depProcessor := GenesisDepositProcessor(state)
 
 	depRoots := make(DepositRoots, 0, len(deps))
 	// Pre-process deposits: get roots
@@ -77,7 +103,7 @@ func InitState(state *BeaconState) (*FullFeaturedState, error) {
 		return nil, errors.New("not enough validators to init full featured BeaconState")
 	}
 	// Process activations
-	state.UpdateEffectiveBalances()
+	state.UpdateEffectiveBalances(1)
 	for _, v := range state.Validators {
 		if v.EffectiveBalance == MAX_EFFECTIVE_BALANCE {
 			v.ActivationEligibilityEpoch = GENESIS_EPOCH
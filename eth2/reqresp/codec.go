@@ -0,0 +1,143 @@
+package reqresp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/golang/snappy"
+	"github.com/protolambda/zssz"
+	"github.com/protolambda/zssz/types"
+	"io"
+	"unicode/utf8"
+)
+
+type ResponseCode uint8
+
+const (
+	SuccessCode        ResponseCode = 0
+	InvalidRequestCode ResponseCode = 1
+	ServerErrorCode    ResponseCode = 2
+)
+
+// Error response chunk, with the message of the responder.
+type ResponseError struct {
+	Code    ResponseCode
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("response error %d: %s", e.Code, e.Message)
+}
+
+// Reads single bytes, to not consume any data of the next chunk.
+type byteReader struct {
+	r io.Reader
+	b [1]byte
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(br.r, br.b[:])
+	return br.b[0], err
+}
+
+// Writes the length of the SSZ encoding of the value as unsigned varint,
+// followed by the SSZ encoding compressed with snappy framing.
+func EncodePayload(w io.Writer, val interface{}, sszTyp types.SSZ) error {
+	size := zssz.SizeOf(val, sszTyp)
+	if size > MAX_CHUNK_SIZE {
+		return fmt.Errorf("payload size %d exceeds maximum chunk size", size)
+	}
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], size)
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	sw := snappy.NewBufferedWriter(w)
+	if _, err := zssz.Encode(sw, val, sszTyp); err != nil {
+		return err
+	}
+	// flushes the last frame, does not close w
+	return sw.Close()
+}
+
+// Reads a payload written by EncodePayload, and decodes it into the value.
+// Reads no further than the end of the payload.
+func DecodePayload(r io.Reader, val interface{}, sszTyp types.SSZ) error {
+	size, err := binary.ReadUvarint(&byteReader{r: r})
+	if err != nil {
+		return err
+	}
+	if size > MAX_CHUNK_SIZE {
+		return fmt.Errorf("payload size %d exceeds maximum chunk size", size)
+	}
+	if size < sszTyp.MinLen() || size > sszTyp.MaxLen() {
+		return fmt.Errorf("payload size %d is out of range for type, expected %d to %d", size, sszTyp.MinLen(), sszTyp.MaxLen())
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(snappy.NewReader(r), buf); err != nil {
+		return fmt.Errorf("could not read compressed payload: %v", err)
+	}
+	return zssz.Decode(bytes.NewReader(buf), size, val, sszTyp)
+}
+
+// Writes a request: requests have no response code.
+func EncodeRequest(w io.Writer, val interface{}, sszTyp types.SSZ) error {
+	return EncodePayload(w, val, sszTyp)
+}
+
+func DecodeRequest(r io.Reader, val interface{}, sszTyp types.SSZ) error {
+	return DecodePayload(r, val, sszTyp)
+}
+
+// Writes a successful response chunk. Multiple chunks may be written for a single request.
+func EncodeResponseChunk(w io.Writer, val interface{}, sszTyp types.SSZ) error {
+	if _, err := w.Write([]byte{byte(SuccessCode)}); err != nil {
+		return err
+	}
+	return EncodePayload(w, val, sszTyp)
+}
+
+// Writes an error response chunk. The message is truncated to the maximum error message length,
+// without splitting a multi-byte character.
+func EncodeErrorChunk(w io.Writer, code ResponseCode, msg string) error {
+	if code == SuccessCode {
+		return errors.New("error response must not have success code")
+	}
+	if _, err := w.Write([]byte{byte(code)}); err != nil {
+		return err
+	}
+	errMsg := ErrorMessage(msg)
+	if limit := errMsg.Limit(); uint64(len(errMsg)) > limit {
+		end := int(limit)
+		for end > 0 && !utf8.RuneStart(errMsg[end]) {
+			end--
+		}
+		errMsg = errMsg[:end]
+	}
+	return EncodePayload(w, &errMsg, ErrorMessageSSZ)
+}
+
+// Reads a response chunk into the value.
+// Returns a *ResponseError if the responder sent an error, and io.EOF if there are no more chunks.
+func DecodeResponseChunk(r io.Reader, val interface{}, sszTyp types.SSZ) error {
+	var code [1]byte
+	if _, err := io.ReadFull(r, code[:]); err != nil {
+		return err
+	}
+	if ResponseCode(code[0]) != SuccessCode {
+		var errMsg ErrorMessage
+		if err := DecodePayload(r, &errMsg, ErrorMessageSSZ); err != nil {
+			return fmt.Errorf("could not decode error message of response code %d: %v", code[0], err)
+		}
+		return &ResponseError{Code: ResponseCode(code[0]), Message: string(errMsg)}
+	}
+	if err := DecodePayload(r, val, sszTyp); err != nil {
+		if err == io.EOF {
+			// the chunk was started, the stream must not end here
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
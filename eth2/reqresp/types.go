@@ -0,0 +1,100 @@
+package reqresp
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zssz"
+)
+
+// Protocol IDs of the req/resp methods, with SSZ-snappy encoding.
+const (
+	StatusProtocol              = "/eth2/beacon_chain/req/status/1/ssz_snappy"
+	GoodbyeProtocol             = "/eth2/beacon_chain/req/goodbye/1/ssz_snappy"
+	BeaconBlocksByRangeProtocol = "/eth2/beacon_chain/req/beacon_blocks_by_range/1/ssz_snappy"
+	BeaconBlocksByRootProtocol  = "/eth2/beacon_chain/req/beacon_blocks_by_root/1/ssz_snappy"
+	PingProtocol                = "/eth2/beacon_chain/req/ping/1/ssz_snappy"
+	MetadataProtocol            = "/eth2/beacon_chain/req/metadata/1/ssz_snappy"
+)
+
+// Maximum size of the uncompressed payload of a request or response chunk.
+const MAX_CHUNK_SIZE = 1 << 20
+
+// Maximum amount of blocks in a single request.
+const MAX_REQUEST_BLOCKS = 1 << 10
+
+var StatusSSZ = zssz.GetSSZ((*Status)(nil))
+
+type Status struct {
+	HeadForkVersion Version
+	FinalizedRoot   Root
+	FinalizedEpoch  Epoch
+	HeadRoot        Root
+	HeadSlot        Slot
+}
+
+var GoodbyeSSZ = zssz.GetSSZ((*Goodbye)(nil))
+
+// Reason for disconnecting
+type Goodbye uint64
+
+const (
+	GoodbyeClientShutdown    Goodbye = 1
+	GoodbyeIrrelevantNetwork Goodbye = 2
+	GoodbyeFaultError        Goodbye = 3
+)
+
+var BeaconBlocksByRangeSSZ = zssz.GetSSZ((*BeaconBlocksByRange)(nil))
+
+type BeaconBlocksByRange struct {
+	HeadBlockRoot Root
+	StartSlot     Slot
+	Count         uint64
+	Step          uint64
+}
+
+var BeaconBlocksByRootSSZ = zssz.GetSSZ((*BeaconBlocksByRoot)(nil))
+
+type BeaconBlocksByRoot []Root
+
+func (*BeaconBlocksByRoot) Limit() uint64 {
+	return MAX_REQUEST_BLOCKS
+}
+
+var PingSSZ = zssz.GetSSZ((*Ping)(nil))
+
+// Sequence number of the metadata of the sender
+type Ping uint64
+
+// Bitvector of the attestation subnets the node is subscribed to, for longer periods of time
+type AttnetBits [8]byte
+
+func (*AttnetBits) BitLen() uint64 {
+	return 64
+}
+
+func (ab *AttnetBits) GetBit(i uint64) bool {
+	return ab[i>>3]&(1<<(i&7)) != 0
+}
+
+func (ab *AttnetBits) SetBit(i uint64, v bool) {
+	if v {
+		ab[i>>3] |= 1 << (i & 7)
+	} else {
+		ab[i>>3] &^= 1 << (i & 7)
+	}
+}
+
+var MetadataSSZ = zssz.GetSSZ((*Metadata)(nil))
+
+type Metadata struct {
+	SeqNumber uint64
+	Attnets   AttnetBits
+}
+
+var ErrorMessageSSZ = zssz.GetSSZ((*ErrorMessage)(nil))
+
+// Message of an error response, expected to be UTF-8 text.
+type ErrorMessage []byte
+
+func (*ErrorMessage) Limit() uint64 {
+	return 256
+}
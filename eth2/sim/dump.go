@@ -0,0 +1,40 @@
+package sim
+
+import (
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zssz"
+	"github.com/protolambda/zssz/types"
+	"os"
+	"path/filepath"
+)
+
+// Writes SSZ encoded states and blocks to a directory. A nil dumper does not write anything.
+type dumper struct {
+	dir string
+}
+
+func (d *dumper) write(name string, val interface{}, sszTyp types.SSZ) error {
+	if d == nil {
+		return nil
+	}
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(d.dir, name+".ssz"))
+	if err != nil {
+		return err
+	}
+	if _, err := zssz.Encode(f, val, sszTyp); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (d *dumper) dumpState(name string, state *phase0.FullFeaturedState) error {
+	return d.write(name, state.BeaconState, phase0.BeaconStateSSZ)
+}
+
+func (d *dumper) dumpBlock(name string, block *phase0.SignedBeaconBlock) error {
+	return d.write(name, block, phase0.SignedBeaconBlockSSZ)
+}
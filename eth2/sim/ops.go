@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"encoding/binary"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	"github.com/protolambda/zrnt/eth2/beacon/exits"
+	"github.com/protolambda/zrnt/eth2/beacon/header"
+	"github.com/protolambda/zrnt/eth2/beacon/slashings/propslash"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zssz/htr"
+	"github.com/protolambda/zssz/merkle"
+)
+
+// Merkle proof of the deposit at the given index, in the deposit tree of the given size.
+func (s *Simulator) depositProof(index DepositIndex, count DepositIndex) (out [DEPOSIT_CONTRACT_TREE_DEPTH + 1]Root) {
+	leaf := func(i uint64) []byte {
+		return s.depositRoots[i][:]
+	}
+	proof := merkle.ConstructProof(htr.HashFn(hashing.GetHashFn()), uint64(count), 1<<DEPOSIT_CONTRACT_TREE_DEPTH, leaf, uint64(index))
+	for j := 0; j < DEPOSIT_CONTRACT_TREE_DEPTH; j++ {
+		out[j] = proof[j]
+	}
+	// mix in the length
+	binary.LittleEndian.PutUint64(out[DEPOSIT_CONTRACT_TREE_DEPTH][:], uint64(count))
+	return
+}
+
+// Creates the attestations of the online validators for the current slot of the state,
+// one aggregate per committee, to be included in later blocks.
+func (s *Simulator) attest() {
+	state := s.State
+	slot := state.Slot
+	epoch := slot.ToEpoch()
+	head := state.HeadRoot()
+	target := Checkpoint{Epoch: epoch, Root: head}
+	if start := epoch.GetStartSlot(); start < slot {
+		target.Root = state.GetBlockRootAtSlot(start)
+	}
+	domain := state.GetDomain(DOMAIN_BEACON_ATTESTER, epoch)
+	committeeCount := state.GetCommitteeCountAtSlot(slot)
+	for index := CommitteeIndex(0); index < CommitteeIndex(committeeCount); index++ {
+		committee := state.GetBeaconCommittee(slot, index)
+		data := AttestationData{
+			Slot:            slot,
+			Index:           index,
+			BeaconBlockRoot: head,
+			Source:          state.CurrentJustifiedCheckpoint,
+			Target:          target,
+		}
+		root := ssz.HashTreeRoot(&data, AttestationDataSSZ)
+		bits := make(CommitteeBits, (len(committee)/8)+1)
+		bits.SetBit(uint64(len(committee)), true)
+		var sigs []BLSSignature
+		for i, v := range committee {
+			if s.Offline[v] || !s.chance(s.Participation) {
+				continue
+			}
+			bits.SetBit(uint64(i), true)
+			sigs = append(sigs, bls.BlsSign(s.key(v), root, domain))
+		}
+		if len(sigs) == 0 {
+			continue
+		}
+		sig, err := bls.BlsAggregateSignatures(sigs)
+		if err != nil {
+			// signatures are created by the simulator, and always valid.
+			panic(err)
+		}
+		s.pending = append(s.pending, &Attestation{AggregationBits: bits, Data: data, Signature: sig})
+	}
+}
+
+// Takes the pending attestations that can be included on top of the state, and drops the expired ones.
+func (s *Simulator) blockAttestations(state *phase0.FullFeaturedState) phase0.Attestations {
+	out := make(phase0.Attestations, 0)
+	remaining := s.pending[:0]
+	for _, att := range s.pending {
+		if att.Data.Slot+SLOTS_PER_EPOCH < state.Slot {
+			continue
+		}
+		// Attestations are created by the simulator, and only the inclusion window has to be checked.
+		// This avoids verifying the signatures twice.
+		if len(out) < MAX_ATTESTATIONS && att.Data.Slot+MIN_ATTESTATION_INCLUSION_DELAY <= state.Slot {
+			out = append(out, *att)
+			continue
+		}
+		remaining = append(remaining, att)
+	}
+	s.pending = remaining
+	return out
+}
+
+// Randomly adds exits and slashings that are valid on top of the state.
+// The proposer and validators that are already part of the block are not chosen.
+func (s *Simulator) addOperations(state *phase0.FullFeaturedState, proposer ValidatorIndex, body *phase0.BeaconBlockBody) {
+	validatorCount := uint64(len(state.Validators))
+	taken := map[ValidatorIndex]bool{proposer: true}
+	pick := func() (ValidatorIndex, bool) {
+		v := ValidatorIndex(s.rng.Uint64() % validatorCount)
+		if taken[v] {
+			return 0, false
+		}
+		taken[v] = true
+		return v, true
+	}
+	epoch := state.Slot.ToEpoch()
+	if s.chance(s.SlashingRate) {
+		if v, ok := pick(); ok {
+			key := s.key(v)
+			domain := state.GetDomain(DOMAIN_BEACON_PROPOSER, epoch)
+			ps := propslash.ProposerSlashing{ProposerIndex: v}
+			for i, h := range []*header.SignedBeaconBlockHeader{&ps.SignedHeader1, &ps.SignedHeader2} {
+				h.Message.Slot = state.Slot
+				h.Message.BodyRoot = Root{byte(i + 1)}
+				h.Signature = bls.BlsSign(key, ssz.HashTreeRoot(h.Message, header.BeaconBlockHeaderSSZ), domain)
+			}
+			if state.ValidateProposerSlashing(&ps) == nil {
+				body.ProposerSlashings = append(body.ProposerSlashings, ps)
+			}
+		}
+	}
+	if s.chance(s.ExitRate) {
+		if v, ok := pick(); ok {
+			exit := exits.SignedVoluntaryExit{Message: exits.VoluntaryExit{Epoch: epoch, ValidatorIndex: v}}
+			exit.Signature = bls.BlsSign(s.key(v), ssz.HashTreeRoot(&exit.Message, exits.VoluntaryExitSSZ),
+				state.GetDomain(DOMAIN_VOLUNTARY_EXIT, epoch))
+			// validators can only exit after being active for PERSISTENT_COMMITTEE_PERIOD
+			if state.ValidateVoluntaryExit(&exit) == nil {
+				body.VoluntaryExits = append(body.VoluntaryExits, exit)
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package sim
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon/attestations"
+	"github.com/protolambda/zrnt/eth2/beacon/deposits"
+	"github.com/protolambda/zrnt/eth2/beacon/eth1"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forks"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zssz"
+	"math/rand"
+)
+
+type Config struct {
+	// Amount of validators at genesis
+	ValidatorCount uint64
+	// Seed for keys and all random choices. Runs with the same config produce the same chain.
+	Seed        int64
+	GenesisTime Timestamp
+	// Chance [0, 1] that an online validator attests to its duty
+	Participation float64
+	// Validators that never attest or propose
+	Offline []ValidatorIndex
+	// Chance [0, 1] that an online proposer misses its slot
+	ProposerMissRate float64
+	// Chance [0, 1] per slot of a new validator deposit on the simulated eth1 chain
+	DepositRate float64
+	// Chance [0, 1] per block of including a voluntary exit, if any validator is eligible.
+	// Validators are only eligible after PERSISTENT_COMMITTEE_PERIOD epochs of activity:
+	// runs from genesis that are shorter than that do not include any exits.
+	ExitRate float64
+	// Chance [0, 1] per block of including a proposer slashing
+	SlashingRate float64
+	// Chance [0, 1] per block of a competing block being produced for the same slot, which is not built upon
+	ForkRate float64
+	// Directory to write SSZ dumps of the genesis state, blocks and epoch states to. No dumps if empty.
+	DumpDir string
+	// The forks to upgrade the state with, at the fork epochs. Nil for a chain with only the phase0 fork.
+	ForkRegistry *forks.Registry
+}
+
+// Simulates a beacon chain, producing signed blocks with operations on top of a state.
+type Simulator struct {
+	Config
+	// The state of the canonical chain, at the slot of the last simulated slot
+	State *phase0.FullFeaturedState
+	// Blocks of the canonical chain, ordered by slot
+	Blocks []*phase0.SignedBeaconBlock
+	// Competing blocks that the canonical chain did not build upon
+	Forks []*phase0.SignedBeaconBlock
+	// Validators that are offline, initialized from the config. May be changed between slots.
+	Offline map[ValidatorIndex]bool
+
+	rng  *rand.Rand
+	keys map[BLSPubkey][32]byte
+	// Deposits on the simulated eth1 chain, and their roots
+	deposits     []deposits.DepositData
+	depositRoots phase0.DepositRoots
+	// The eth1 data proposers currently vote for
+	eth1Vote eth1.Eth1Data
+	// Attestations not yet included in a block
+	pending []*attestations.Attestation
+	dumper  *dumper
+}
+
+// Creates a genesis state with the configured validators, ready to simulate from.
+func NewSimulator(cfg Config) (*Simulator, error) {
+	s := &Simulator{
+		Config:  cfg,
+		Offline: make(map[ValidatorIndex]bool),
+		rng:     rand.New(rand.NewSource(cfg.Seed)),
+		keys:    make(map[BLSPubkey][32]byte),
+	}
+	for _, i := range cfg.Offline {
+		s.Offline[i] = true
+	}
+	deps := make([]deposits.Deposit, 0, cfg.ValidatorCount)
+	for i := uint64(0); i < cfg.ValidatorCount; i++ {
+		data := s.newDeposit()
+		deps = append(deps, deposits.Deposit{Data: *data})
+	}
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], uint64(cfg.Seed))
+	state, err := phase0.GenesisFromEth1(sha256.Sum256(seed[:]), 0, deps, false)
+	if err != nil {
+		return nil, err
+	}
+	state.GenesisTime = cfg.GenesisTime
+	s.State = state
+	s.eth1Vote = state.Eth1Data
+	if cfg.DumpDir != "" {
+		s.dumper = &dumper{dir: cfg.DumpDir}
+		if err := s.dumper.dumpState("genesis", state); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Creates a deposit for a new validator on the simulated eth1 chain.
+func (s *Simulator) newDeposit() *deposits.DepositData {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], uint64(s.Seed))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(len(s.deposits)))
+	key := sha256.Sum256(buf[:])
+	// keep the key below the curve order
+	key[0] &= 0x1f
+	data := *deposits.NewDepositData(key, bls.BlsSecretToPubkey(key), MAX_EFFECTIVE_BALANCE)
+	s.keys[data.Pubkey] = key
+	s.deposits = append(s.deposits, data)
+	s.depositRoots = append(s.depositRoots, ssz.HashTreeRoot(&data, deposits.DepositDataSSZ))
+	return &data
+}
+
+func (s *Simulator) key(index ValidatorIndex) [32]byte {
+	return s.keys[s.State.Validators[index].Pubkey]
+}
+
+func (s *Simulator) chance(p float64) bool {
+	return p > 0 && s.rng.Float64() < p
+}
+
+// Simulates the next slot: eth1 deposits, the block proposal (if any) and the attestations for the slot.
+func (s *Simulator) NextSlot() error {
+	slot := s.State.Slot + 1
+	if slot%SLOTS_PER_ETH1_VOTING_PERIOD == 0 {
+		// the eth1 data of the last period is voted for the during next period
+		s.eth1Vote = eth1.Eth1Data{
+			DepositRoot:  ssz.HashTreeRoot(&s.depositRoots, phase0.DepositRootsSSZ),
+			DepositCount: DepositIndex(len(s.deposits)),
+			BlockHash:    sha256.Sum256(s.eth1Vote.BlockHash[:]),
+		}
+	}
+	if s.chance(s.DepositRate) {
+		s.newDeposit()
+	}
+
+	state, err := s.ForkRegistry.ProcessPhase0Slots(s.State, slot)
+	if err != nil {
+		return err
+	}
+	s.State = state
+	proposer := s.State.GetBeaconProposerIndex(slot)
+	// slashed validators cannot propose
+	if !s.Offline[proposer] && !s.State.Validators[proposer].Slashed && !s.chance(s.ProposerMissRate) {
+		if s.chance(s.ForkRate) {
+			alt, err := s.State.Copy()
+			if err != nil {
+				return err
+			}
+			fork, err := s.produceBlock(alt, slot, Root{0xf0, 0x4c}, false)
+			if err != nil {
+				return fmt.Errorf("failed to produce fork block at slot %d: %w", slot, err)
+			}
+			s.Forks = append(s.Forks, fork)
+			if err := s.dumper.dumpBlock(fmt.Sprintf("fork_%d", len(s.Forks)-1), fork); err != nil {
+				return err
+			}
+		}
+		block, err := s.produceBlock(s.State, slot, Root{}, true)
+		if err != nil {
+			return fmt.Errorf("failed to produce block at slot %d: %w", slot, err)
+		}
+		s.Blocks = append(s.Blocks, block)
+		if err := s.dumper.dumpBlock(fmt.Sprintf("blocks_%d", len(s.Blocks)-1), block); err != nil {
+			return err
+		}
+	}
+	if slot%SLOTS_PER_EPOCH == 0 {
+		if err := s.dumper.dumpState(fmt.Sprintf("state_epoch_%d", slot.ToEpoch()), s.State); err != nil {
+			return err
+		}
+	}
+	s.attest()
+	return nil
+}
+
+// Simulates slots up to and including the given slot.
+func (s *Simulator) RunUntil(slot Slot) error {
+	for s.State.Slot < slot {
+		if err := s.NextSlot(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Simulates the given amount of epochs, from the current slot on.
+func (s *Simulator) RunEpochs(epochs Epoch) error {
+	return s.RunUntil(s.State.Slot + Slot(epochs)*SLOTS_PER_EPOCH)
+}
+
+// Produces a block at the given slot on top of the state, applying it to the state.
+// The state must already be processed up to the slot.
+// The block includes pending attestations and random operations only if withOps is true.
+func (s *Simulator) produceBlock(state *phase0.FullFeaturedState, slot Slot, graffiti Root, withOps bool) (*phase0.SignedBeaconBlock, error) {
+	proposer := state.GetBeaconProposerIndex(slot)
+	key := s.key(proposer)
+	epoch := slot.ToEpoch()
+
+	block := &phase0.SignedBeaconBlock{}
+	block.Message.Slot = slot
+	block.Message.ParentRoot = state.HeadRoot()
+	body := &block.Message.Body
+	body.RandaoReveal = bls.BlsSign(key, ssz.HashTreeRoot(epoch, epochSSZ), state.GetDomain(DOMAIN_RANDAO, epoch))
+	body.Eth1Data = s.eth1Vote
+	body.Graffiti = graffiti
+	body.Deposits = s.blockDeposits(state, body.Eth1Data)
+	if withOps {
+		body.Attestations = s.blockAttestations(state)
+		s.addOperations(state, proposer, body)
+	}
+
+	blockProc := &phase0.BlockProcessFeature{Block: block, Meta: state}
+	if err := blockProc.Process(); err != nil {
+		return nil, err
+	}
+	block.Message.StateRoot = state.StateRoot()
+	block.Signature = bls.BlsSign(key, ssz.HashTreeRoot(&block.Message, phase0.BeaconBlockSSZ),
+		state.GetDomain(DOMAIN_BEACON_PROPOSER, epoch))
+	return block, nil
+}
+
+var epochSSZ = zssz.GetSSZ((*Epoch)(nil))
+
+// The deposits a block with the given eth1 vote has to include.
+func (s *Simulator) blockDeposits(state *phase0.FullFeaturedState, vote eth1.Eth1Data) phase0.Deposits {
+	// predict the eth1 data after the vote of the block, deposits are processed after the vote.
+	eth1Data := state.Eth1Data
+	count := Slot(1)
+	for _, v := range state.Eth1DataVotes {
+		if v == vote {
+			count++
+		}
+	}
+	if count<<1 > SLOTS_PER_ETH1_VOTING_PERIOD {
+		eth1Data = vote
+	}
+	start := state.DepositIndex
+	end := eth1Data.DepositCount
+	if end > start+MAX_DEPOSITS {
+		end = start + MAX_DEPOSITS
+	}
+	out := make(phase0.Deposits, 0, end-start)
+	for i := start; i < end; i++ {
+		out = append(out, deposits.Deposit{
+			Proof: s.depositProof(i, eth1Data.DepositCount),
+			Data:  s.deposits[i],
+		})
+	}
+	return out
+}
@@ -0,0 +1,344 @@
+package statediff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	. "github.com/protolambda/zrnt/eth2/beacon/eth1"
+	. "github.com/protolambda/zrnt/eth2/beacon/finality"
+	. "github.com/protolambda/zrnt/eth2/beacon/header"
+	. "github.com/protolambda/zrnt/eth2/beacon/history"
+	. "github.com/protolambda/zrnt/eth2/beacon/validator"
+	. "github.com/protolambda/zrnt/eth2/beacon/versioning"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zssz"
+)
+
+var StateDiffSSZ = zssz.GetSSZ((*StateDiff)(nil))
+
+var ErrShrinkingRegistry = errors.New("validator registry cannot shrink")
+
+// Update of a single root in a vector of roots, e.g. BlockRoots.
+type RootUpdate struct {
+	Index uint64
+	Root  Root
+}
+
+type HistoricalRootUpdates []RootUpdate
+
+func (*HistoricalRootUpdates) Limit() uint64 {
+	return uint64(SLOTS_PER_HISTORICAL_ROOT)
+}
+
+type RandaoMixUpdates []RootUpdate
+
+func (*RandaoMixUpdates) Limit() uint64 {
+	return uint64(EPOCHS_PER_HISTORICAL_VECTOR)
+}
+
+type SlashingUpdate struct {
+	Index  uint64
+	Amount Gwei
+}
+
+type SlashingUpdates []SlashingUpdate
+
+func (*SlashingUpdates) Limit() uint64 {
+	return uint64(EPOCHS_PER_SLASHINGS_VECTOR)
+}
+
+// A changed validator, or a new validator if the index is at the end of the registry.
+type ValidatorUpdate struct {
+	Index     ValidatorIndex
+	Validator Validator
+}
+
+type ValidatorUpdates []ValidatorUpdate
+
+func (*ValidatorUpdates) Limit() uint64 {
+	return VALIDATOR_REGISTRY_LIMIT
+}
+
+// A balance change of a validator. The balance of a new validator is a reward.
+type BalanceDelta struct {
+	Index   ValidatorIndex
+	Reward  Gwei
+	Penalty Gwei
+}
+
+type BalanceDeltas []BalanceDelta
+
+func (*BalanceDeltas) Limit() uint64 {
+	return VALIDATOR_REGISTRY_LIMIT
+}
+
+// Changes to a list that is mostly appended to:
+// the first Keep elements of the old list are kept, followed by the appended elements.
+type HistoricalRootsDiff struct {
+	Keep     uint64
+	Appended HistoricalRoots
+}
+
+type Eth1DataVotesDiff struct {
+	Keep     uint64
+	Appended Eth1DataVotes
+}
+
+type PendingAttestationsDiff struct {
+	Keep     uint64
+	Appended EpochPendingAttestations
+}
+
+// A compact diff between two beacon states. Fields that are small are included as a whole,
+// vectors and the registry are included as sparse updates, and lists as kept prefix plus appended elements.
+type StateDiff struct {
+	// Roots of the state the diff applies to, and the resulting state
+	PreRoot  Root
+	PostRoot Root
+
+	GenesisTime Timestamp
+	Slot        Slot
+	Fork        Fork
+
+	LatestBlockHeader BeaconBlockHeader
+
+	BlockRoots      HistoricalRootUpdates
+	StateRoots      HistoricalRootUpdates
+	HistoricalRoots HistoricalRootsDiff
+
+	Eth1Data      Eth1Data
+	Eth1DataVotes Eth1DataVotesDiff
+	DepositIndex  DepositIndex
+
+	Validators ValidatorUpdates
+	Balances   BalanceDeltas
+
+	RandaoMixes RandaoMixUpdates
+	Slashings   SlashingUpdates
+
+	// If the current epoch attestations were rotated into the previous epoch attestations,
+	// before the attestation diffs apply.
+	RotateAttestations        bool
+	PreviousEpochAttestations PendingAttestationsDiff
+	CurrentEpochAttestations  PendingAttestationsDiff
+
+	JustificationBits           JustificationBits
+	PreviousJustifiedCheckpoint Checkpoint
+	CurrentJustifiedCheckpoint  Checkpoint
+	FinalizedCheckpoint         Checkpoint
+}
+
+func diffRoots(pre []Root, post []Root) (out []RootUpdate) {
+	for i := range post {
+		if pre[i] != post[i] {
+			out = append(out, RootUpdate{Index: uint64(i), Root: post[i]})
+		}
+	}
+	return
+}
+
+func commonPrefix(preLen int, postLen int, equal func(i int) bool) (keep uint64) {
+	for int(keep) < preLen && int(keep) < postLen && equal(int(keep)) {
+		keep++
+	}
+	return
+}
+
+func diffAttestations(pre EpochPendingAttestations, post EpochPendingAttestations) PendingAttestationsDiff {
+	keep := commonPrefix(len(pre), len(post), func(i int) bool {
+		a, b := pre[i], post[i]
+		return a.Data == b.Data && a.InclusionDelay == b.InclusionDelay &&
+			a.ProposerIndex == b.ProposerIndex && bytes.Equal(a.AggregationBits, b.AggregationBits)
+	})
+	return PendingAttestationsDiff{Keep: keep, Appended: post[keep:]}
+}
+
+// Computes the diff from the pre-state to the post-state.
+// The appended list elements in the diff are shared with the post-state, not copied.
+func Diff(pre *phase0.BeaconState, post *phase0.BeaconState) (*StateDiff, error) {
+	if len(post.Validators) < len(pre.Validators) || len(post.Balances) < len(pre.Balances) {
+		return nil, ErrShrinkingRegistry
+	}
+	d := &StateDiff{
+		PreRoot:                     pre.StateRoot(),
+		PostRoot:                    post.StateRoot(),
+		GenesisTime:                 post.GenesisTime,
+		Slot:                        post.Slot,
+		Fork:                        post.Fork,
+		LatestBlockHeader:           post.LatestBlockHeader,
+		BlockRoots:                  diffRoots(pre.BlockRoots[:], post.BlockRoots[:]),
+		StateRoots:                  diffRoots(pre.StateRoots[:], post.StateRoots[:]),
+		Eth1Data:                    post.Eth1Data,
+		DepositIndex:                post.DepositIndex,
+		RandaoMixes:                 diffRoots(pre.RandaoMixes[:], post.RandaoMixes[:]),
+		JustificationBits:           post.JustificationBits,
+		PreviousJustifiedCheckpoint: post.PreviousJustifiedCheckpoint,
+		CurrentJustifiedCheckpoint:  post.CurrentJustifiedCheckpoint,
+		FinalizedCheckpoint:         post.FinalizedCheckpoint,
+	}
+
+	keep := commonPrefix(len(pre.HistoricalRoots), len(post.HistoricalRoots), func(i int) bool {
+		return pre.HistoricalRoots[i] == post.HistoricalRoots[i]
+	})
+	d.HistoricalRoots = HistoricalRootsDiff{Keep: keep, Appended: post.HistoricalRoots[keep:]}
+
+	keep = commonPrefix(len(pre.Eth1DataVotes), len(post.Eth1DataVotes), func(i int) bool {
+		return pre.Eth1DataVotes[i] == post.Eth1DataVotes[i]
+	})
+	d.Eth1DataVotes = Eth1DataVotesDiff{Keep: keep, Appended: post.Eth1DataVotes[keep:]}
+
+	for i, v := range post.Validators {
+		if i >= len(pre.Validators) || *pre.Validators[i] != *v {
+			d.Validators = append(d.Validators, ValidatorUpdate{Index: ValidatorIndex(i), Validator: *v})
+		}
+	}
+	for i, b := range post.Balances {
+		var preBal Gwei
+		if i < len(pre.Balances) {
+			preBal = pre.Balances[i]
+		}
+		if b > preBal {
+			d.Balances = append(d.Balances, BalanceDelta{Index: ValidatorIndex(i), Reward: b - preBal})
+		} else if b < preBal {
+			d.Balances = append(d.Balances, BalanceDelta{Index: ValidatorIndex(i), Penalty: preBal - b})
+		} else if i >= len(pre.Balances) {
+			// new validator with a zero balance, still needs to be appended
+			d.Balances = append(d.Balances, BalanceDelta{Index: ValidatorIndex(i)})
+		}
+	}
+
+	for i := range post.Slashings {
+		if pre.Slashings[i] != post.Slashings[i] {
+			d.Slashings = append(d.Slashings, SlashingUpdate{Index: uint64(i), Amount: post.Slashings[i]})
+		}
+	}
+
+	prevBase := pre.PreviousEpochAttestations
+	currBase := pre.CurrentEpochAttestations
+	// The attestations rotate at the epoch transition
+	if pre.Slot.ToEpoch() < post.Slot.ToEpoch() {
+		d.RotateAttestations = true
+		prevBase = pre.CurrentEpochAttestations
+		currBase = nil
+	}
+	d.PreviousEpochAttestations = diffAttestations(prevBase, post.PreviousEpochAttestations)
+	d.CurrentEpochAttestations = diffAttestations(currBase, post.CurrentEpochAttestations)
+	return d, nil
+}
+
+func applyRoots(roots []Root, updates []RootUpdate) error {
+	for _, u := range updates {
+		if u.Index >= uint64(len(roots)) {
+			return fmt.Errorf("root update index %d out of range", u.Index)
+		}
+		roots[u.Index] = u.Root
+	}
+	return nil
+}
+
+func applyAttestations(base EpochPendingAttestations, d *PendingAttestationsDiff) (EpochPendingAttestations, error) {
+	if d.Keep > uint64(len(base)) {
+		return nil, fmt.Errorf("cannot keep %d attestations, only %d available", d.Keep, len(base))
+	}
+	out := make(EpochPendingAttestations, 0, d.Keep+uint64(len(d.Appended)))
+	out = append(out, base[:d.Keep]...)
+	for _, att := range d.Appended {
+		a := *att
+		out = append(out, &a)
+	}
+	return out, nil
+}
+
+// Applies the diff to the state, modifying it in-place to become the newer state.
+// If verify is true, the state root is checked against the pre- and post-root of the diff.
+// The state is left in an undefined state if an error is returned.
+func (d *StateDiff) Apply(state *phase0.BeaconState, verify bool) error {
+	if verify {
+		if root := state.StateRoot(); root != d.PreRoot {
+			return fmt.Errorf("diff applies to state %x, but got state %x", d.PreRoot, root)
+		}
+	}
+	state.GenesisTime = d.GenesisTime
+	state.Slot = d.Slot
+	state.Fork = d.Fork
+	state.LatestBlockHeader = d.LatestBlockHeader
+
+	if err := applyRoots(state.BlockRoots[:], d.BlockRoots); err != nil {
+		return fmt.Errorf("invalid block roots: %v", err)
+	}
+	if err := applyRoots(state.StateRoots[:], d.StateRoots); err != nil {
+		return fmt.Errorf("invalid state roots: %v", err)
+	}
+	if d.HistoricalRoots.Keep > uint64(len(state.HistoricalRoots)) {
+		return fmt.Errorf("cannot keep %d historical roots, only %d available", d.HistoricalRoots.Keep, len(state.HistoricalRoots))
+	}
+	state.HistoricalRoots = append(state.HistoricalRoots[:d.HistoricalRoots.Keep:d.HistoricalRoots.Keep], d.HistoricalRoots.Appended...)
+
+	state.Eth1Data = d.Eth1Data
+	if d.Eth1DataVotes.Keep > uint64(len(state.Eth1DataVotes)) {
+		return fmt.Errorf("cannot keep %d eth1 votes, only %d available", d.Eth1DataVotes.Keep, len(state.Eth1DataVotes))
+	}
+	state.Eth1DataVotes = append(state.Eth1DataVotes[:d.Eth1DataVotes.Keep:d.Eth1DataVotes.Keep], d.Eth1DataVotes.Appended...)
+	state.DepositIndex = d.DepositIndex
+
+	for _, u := range d.Validators {
+		v := u.Validator
+		if u.Index < ValidatorIndex(len(state.Validators)) {
+			state.Validators[u.Index] = &v
+		} else if u.Index == ValidatorIndex(len(state.Validators)) {
+			state.Validators = append(state.Validators, &v)
+		} else {
+			return fmt.Errorf("validator update index %d is not in registry of size %d", u.Index, len(state.Validators))
+		}
+	}
+	for _, b := range d.Balances {
+		if b.Index == ValidatorIndex(len(state.Balances)) {
+			state.Balances = append(state.Balances, 0)
+		} else if b.Index > ValidatorIndex(len(state.Balances)) {
+			return fmt.Errorf("balance delta index %d is not in registry of size %d", b.Index, len(state.Balances))
+		}
+		state.Balances[b.Index] += b.Reward
+		if b.Penalty > state.Balances[b.Index] {
+			return fmt.Errorf("balance penalty %d of validator %d exceeds balance", b.Penalty, b.Index)
+		}
+		state.Balances[b.Index] -= b.Penalty
+	}
+
+	if err := applyRoots(state.RandaoMixes[:], d.RandaoMixes); err != nil {
+		return fmt.Errorf("invalid randao mixes: %v", err)
+	}
+	for _, u := range d.Slashings {
+		if u.Index >= uint64(len(state.Slashings)) {
+			return fmt.Errorf("slashing update index %d out of range", u.Index)
+		}
+		state.Slashings[u.Index] = u.Amount
+	}
+
+	if d.RotateAttestations {
+		state.RotateEpochAttestations()
+	}
+	prev, err := applyAttestations(state.PreviousEpochAttestations, &d.PreviousEpochAttestations)
+	if err != nil {
+		return fmt.Errorf("invalid previous epoch attestations: %v", err)
+	}
+	curr, err := applyAttestations(state.CurrentEpochAttestations, &d.CurrentEpochAttestations)
+	if err != nil {
+		return fmt.Errorf("invalid current epoch attestations: %v", err)
+	}
+	state.PreviousEpochAttestations = prev
+	state.CurrentEpochAttestations = curr
+
+	state.JustificationBits = d.JustificationBits
+	state.PreviousJustifiedCheckpoint = d.PreviousJustifiedCheckpoint
+	state.CurrentJustifiedCheckpoint = d.CurrentJustifiedCheckpoint
+	state.FinalizedCheckpoint = d.FinalizedCheckpoint
+
+	if verify {
+		if root := state.StateRoot(); root != d.PostRoot {
+			return fmt.Errorf("diff should result in state %x, but got state %x", d.PostRoot, root)
+		}
+	}
+	return nil
+}
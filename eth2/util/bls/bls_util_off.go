@@ -2,7 +2,10 @@
 
 package bls
 
-import . "github.com/protolambda/zrnt/eth2/core"
+import (
+	"crypto/sha256"
+	. "github.com/protolambda/zrnt/eth2/core"
+)
 
 const BLS_ACTIVE = false
 
@@ -12,6 +15,24 @@ func BlsVerify(pubkey BLSPubkey, messageHash Root, signature BLSSignature, domai
 	return true
 }
 
+func BlsSign(secretKey [32]byte, messageHash Root, domain BLSDomain) BLSSignature {
+	// Temporary: just return an empty signature, verification always passes.
+	return BLSSignature{}
+}
+
+func BlsSecretToPubkey(secretKey [32]byte) BLSPubkey {
+	// Temporary: a hash of the secret, to keep pubkeys unique.
+	h := sha256.Sum256(secretKey[:])
+	out := BLSPubkey{}
+	copy(out[:], h[:])
+	return out
+}
+
+func BlsAggregateSignatures(signatures []BLSSignature) (BLSSignature, error) {
+	// Temporary: just return an empty signature, verification always passes.
+	return BLSSignature{}, nil
+}
+
 func BlsAggregatePubkeys(pubkeys []BLSPubkey) BLSPubkey {
 	// TODO aggregate pubkeys with BLS
 	// Temporary: just return an empty key (TODO: or is XOR better temporarily?)
@@ -3,24 +3,65 @@
 package bls
 
 import (
+	"fmt"
 	phbls "github.com/phoreproject/bls/g1pubs"
 	. "github.com/protolambda/zrnt/eth2/core"
 )
 
 const BLS_ACTIVE = true
 
+// The BLS library panics on some invalid points instead of returning an error, e.g. on untrusted block contents.
+func deserializePubkey(pubkey BLSPubkey) (pub *phbls.PublicKey, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pub, err = nil, fmt.Errorf("invalid pubkey: %v", r)
+		}
+	}()
+	return phbls.DeserializePublicKey(pubkey)
+}
+
+func deserializeSignature(signature BLSSignature) (sig *phbls.Signature, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			sig, err = nil, fmt.Errorf("invalid signature: %v", r)
+		}
+	}()
+	return phbls.DeserializeSignature(signature)
+}
+
 func BlsVerify(pubkey BLSPubkey, messageHash Root, signature BLSSignature, domain BLSDomain) bool {
-	pub, err := phbls.DeserializePublicKey(pubkey)
+	pub, err := deserializePubkey(pubkey)
 	if err != nil {
 		return false
 	}
-	sig, err := phbls.DeserializeSignature(signature)
+	sig, err := deserializeSignature(signature)
 	if err != nil {
 		return false
 	}
 	return phbls.VerifyWithDomain(messageHash, pub, sig, domain)
 }
 
+func BlsSign(secretKey [32]byte, messageHash Root, domain BLSDomain) BLSSignature {
+	priv := phbls.DeserializeSecretKey(secretKey)
+	return phbls.SignWithDomain(messageHash, priv, domain).Serialize()
+}
+
+func BlsSecretToPubkey(secretKey [32]byte) BLSPubkey {
+	return phbls.PrivToPub(phbls.DeserializeSecretKey(secretKey)).Serialize()
+}
+
+func BlsAggregateSignatures(signatures []BLSSignature) (BLSSignature, error) {
+	sigs := make([]*phbls.Signature, 0, len(signatures))
+	for i := range signatures {
+		sig, err := deserializeSignature(signatures[i])
+		if err != nil {
+			return BLSSignature{}, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return phbls.AggregateSignatures(sigs).Serialize(), nil
+}
+
 func BlsAggregatePubkeys(pubkeys []BLSPubkey) BLSPubkey {
 	agpub := phbls.AggregatePublicKeys(parsePubkeys(pubkeys))
 	return agpub.Serialize()
@@ -29,7 +70,7 @@ func BlsAggregatePubkeys(pubkeys []BLSPubkey) BLSPubkey {
 func parsePubkeys(pubkeys []BLSPubkey) []*phbls.PublicKey {
 	pubs := make([]*phbls.PublicKey, 0, len(pubkeys))
 	for i := range pubkeys {
-		p, err := phbls.DeserializePublicKey(pubkeys[i])
+		p, err := deserializePubkey(pubkeys[i])
 		if err != nil {
 			return nil
 		}
@@ -47,7 +88,7 @@ func BlsVerifyMultiple(pubkeys []BLSPubkey, messageHashes []Root, signature BLSS
 		return false
 	}
 
-	sig, err := phbls.DeserializeSignature(signature)
+	sig, err := deserializeSignature(signature)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,31 @@
+package parallel
+
+import "sync"
+
+// Range calls fn for contiguous chunks that together cover [0, count),
+// and splits the chunks over up to the given amount of workers.
+// Returns after all chunks are processed.
+// With 1 or less workers, fn is called once for the full range, on the calling goroutine.
+func Range(workers int, count uint64, fn func(start uint64, end uint64)) {
+	if workers <= 1 || count <= 1 {
+		fn(0, count)
+		return
+	}
+	if uint64(workers) > count {
+		workers = int(count)
+	}
+	chunkSize := (count + uint64(workers) - 1) / uint64(workers)
+	var wg sync.WaitGroup
+	for start := uint64(0); start < count; start += chunkSize {
+		end := start + chunkSize
+		if end > count {
+			end = count
+		}
+		wg.Add(1)
+		go func(start uint64, end uint64) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
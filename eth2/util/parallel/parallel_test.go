@@ -0,0 +1,45 @@
+package parallel
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+var rangeTests = []struct {
+	workers int
+	count   uint64
+}{
+	{0, 0},
+	{1, 0},
+	{4, 0},
+	{1, 1},
+	{4, 1},
+	{4, 3},
+	{4, 4},
+	{4, 10},
+	{3, 1000},
+	{16, 1001},
+}
+
+func TestRange(t *testing.T) {
+	for _, testCase := range rangeTests {
+		t.Run(fmt.Sprintf("Range: %d workers, %d items", testCase.workers, testCase.count), func(tt *testing.T) {
+			visits := make([]uint32, testCase.count, testCase.count)
+			Range(testCase.workers, testCase.count, func(start uint64, end uint64) {
+				if start > end || end > testCase.count {
+					tt.Errorf("invalid chunk: %d - %d", start, end)
+					return
+				}
+				for i := start; i < end; i++ {
+					atomic.AddUint32(&visits[i], 1)
+				}
+			})
+			for i, v := range visits {
+				if v != 1 {
+					tt.Errorf("item %d visited %d times", i, v)
+				}
+			}
+		})
+	}
+}
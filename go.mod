@@ -1,8 +1,9 @@
 module github.com/protolambda/zrnt
 
-go 1.12
+go 1.13
 
 require (
+	github.com/golang/snappy v0.0.1
 	github.com/minio/sha256-simd v0.1.0
 	github.com/phoreproject/bls v0.0.0-20190821133044-da95d4798b09
 	github.com/protolambda/messagediff v1.3.0
@@ -46,6 +46,8 @@ const BLS_WITHDRAWAL_PREFIX = 0
 
 const SECONDS_PER_SLOT = 6
 
+const SECONDS_PER_ETH1_BLOCK = 14
+
 const MIN_ATTESTATION_INCLUSION_DELAY = 1
 
 const SLOTS_PER_EPOCH = 8
@@ -110,7 +112,11 @@ const DOMAIN_DEPOSIT = 0x03000000
 
 const DOMAIN_VOLUNTARY_EXIT = 0x04000000
 
-const DOMAIN_CUSTODY_BIT_CHALLENGE = 0x06000000
+const DOMAIN_SELECTION_PROOF = 0x05000000
+
+const DOMAIN_AGGREGATE_AND_PROOF = 0x06000000
+
+const DOMAIN_CUSTODY_BIT_SLASHING = 0x83000000
 
 const DOMAIN_SHARD_PROPOSER = 0x80000000
 
@@ -0,0 +1,142 @@
+package benches
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/phoreproject/bls/g1pubs"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	. "github.com/protolambda/zrnt/eth2/core"
+	. "github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zrnt/presets/generated"
+	"github.com/protolambda/zssz"
+	"github.com/protolambda/zssz/types"
+	"testing"
+)
+
+func TestAggregateAndProofSigning(t *testing.T) {
+	if !bls.BLS_ACTIVE {
+		t.Skip("BLS is disabled")
+	}
+	count := uint64(SLOTS_PER_EPOCH)
+	keys := make([][32]byte, count)
+	validators := CreateTestValidators(count, MAX_EFFECTIVE_BALANCE)
+	for i := range keys {
+		keys[i] = [32]byte{31: byte(i + 1)}
+		validators[i].Pubkey = g1pubs.PrivToPub(g1pubs.DeserializeSecretKey(keys[i])).Serialize()
+	}
+	state, err := KickStartState(Root{123}, 1564000000, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slot := Slot(3)
+	proof := state.SignSelectionProof(keys[2], slot)
+	if !state.VerifySelectionProof(2, slot, proof) {
+		t.Error("selection proof does not verify")
+	}
+	if state.VerifySelectionProof(1, slot, proof) || state.VerifySelectionProof(2, slot+1, proof) {
+		t.Error("selection proof verifies for other validator or slot")
+	}
+	// committees smaller than the aggregator target select every member
+	if !state.IsAggregatorOf(slot, 0, proof) {
+		t.Error("expected member of small committee to be aggregator")
+	}
+
+	msg := &AggregateAndProof{AggregatorIndex: 2, SelectionProof: proof}
+	msg.Aggregate.Data.Slot = slot
+	signed := state.SignAggregateAndProof(keys[2], msg)
+	if !state.VerifyAggregateAndProofSignature(signed) {
+		t.Error("aggregate and proof signature does not verify")
+	}
+	signed.Message.Aggregate.Data.Index = 1
+	if state.VerifyAggregateAndProofSignature(signed) {
+		t.Error("aggregate and proof signature verifies for changed message")
+	}
+}
+
+func TestAggregationDomains(t *testing.T) {
+	// values of the v0.11 configurations
+	if generated.DOMAIN_SELECTION_PROOF != 0x05000000 {
+		t.Errorf("unexpected selection proof domain: %08x", generated.DOMAIN_SELECTION_PROOF)
+	}
+	if generated.DOMAIN_AGGREGATE_AND_PROOF != 0x06000000 {
+		t.Errorf("unexpected aggregate and proof domain: %08x", generated.DOMAIN_AGGREGATE_AND_PROOF)
+	}
+	domains := map[string]uint32{
+		"DOMAIN_BEACON_PROPOSER":      generated.DOMAIN_BEACON_PROPOSER,
+		"DOMAIN_BEACON_ATTESTER":      generated.DOMAIN_BEACON_ATTESTER,
+		"DOMAIN_RANDAO":               generated.DOMAIN_RANDAO,
+		"DOMAIN_DEPOSIT":              generated.DOMAIN_DEPOSIT,
+		"DOMAIN_VOLUNTARY_EXIT":       generated.DOMAIN_VOLUNTARY_EXIT,
+		"DOMAIN_SELECTION_PROOF":      generated.DOMAIN_SELECTION_PROOF,
+		"DOMAIN_AGGREGATE_AND_PROOF":  generated.DOMAIN_AGGREGATE_AND_PROOF,
+		"DOMAIN_CUSTODY_BIT_SLASHING": generated.DOMAIN_CUSTODY_BIT_SLASHING,
+		"DOMAIN_SHARD_PROPOSER":       generated.DOMAIN_SHARD_PROPOSER,
+		"DOMAIN_SHARD_ATTESTER":       generated.DOMAIN_SHARD_ATTESTER,
+	}
+	seen := make(map[uint32]string)
+	for name, v := range domains {
+		if other, ok := seen[v]; ok {
+			t.Errorf("%s and %s share domain %08x", name, other, v)
+		}
+		seen[v] = name
+	}
+}
+
+// The ssz_static vectors of v0.9.3 do not include the aggregation containers, check the SSZ round-trip instead.
+func TestAggregateAndProofSSZ(t *testing.T) {
+	bits := make(CommitteeBits, 2)
+	bits.SetBit(9, true)
+	bits.SetBit(3, true)
+	signed := &SignedAggregateAndProof{
+		Message: AggregateAndProof{
+			AggregatorIndex: 42,
+			Aggregate: Attestation{
+				AggregationBits: bits,
+				Data:            AttestationData{Slot: 7, Index: 1, BeaconBlockRoot: Root{1}},
+				Signature:       BLSSignature{2},
+			},
+			SelectionProof: BLSSignature{3},
+		},
+		Signature: BLSSignature{4},
+	}
+	for _, c := range []struct {
+		name  string
+		val   interface{}
+		alloc func() interface{}
+		typ   types.SSZ
+	}{
+		{"AggregateAndProof", &signed.Message, func() interface{} { return new(AggregateAndProof) }, AggregateAndProofSSZ},
+		{"SignedAggregateAndProof", signed, func() interface{} { return new(SignedAggregateAndProof) }, SignedAggregateAndProofSSZ},
+	} {
+		var buf bytes.Buffer
+		if _, err := zssz.Encode(&buf, c.val, c.typ); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		encoded := append([]byte(nil), buf.Bytes()...)
+		out := c.alloc()
+		if err := zssz.Decode(&buf, uint64(len(encoded)), out, c.typ); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var again bytes.Buffer
+		if _, err := zssz.Encode(&again, out, c.typ); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !bytes.Equal(again.Bytes(), encoded) {
+			t.Errorf("%s: encoding changed after decoding", c.name)
+		}
+		if ssz.HashTreeRoot(out, c.typ) != ssz.HashTreeRoot(c.val, c.typ) {
+			t.Errorf("%s: hash tree root changed after decoding", c.name)
+		}
+	}
+	// the offset of the variable-size aggregate follows the fixed-size aggregator index
+	var buf bytes.Buffer
+	if _, err := zssz.Encode(&buf, &signed.Message, AggregateAndProofSSZ); err != nil {
+		t.Fatal(err)
+	}
+	if offset := binary.LittleEndian.Uint32(buf.Bytes()[8:12]); offset != 8+4+96 {
+		t.Errorf("unexpected aggregate offset: %d", offset)
+	}
+}
@@ -0,0 +1,90 @@
+package benches
+
+import (
+	"github.com/protolambda/zrnt/eth2/archive"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	if SLOTS_PER_HISTORICAL_ROOT > 64 {
+		t.Skip("too many slots per historical root to build a test chain")
+	}
+	chain, keys := createKeyedTestState(t, uint64(SLOTS_PER_EPOCH)*4)
+	arch, err := archive.NewArchive(chain, SLOTS_PER_EPOCH*2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the expected state root of every slot, with blocks at some of the slots
+	end := SLOTS_PER_HISTORICAL_ROOT*2 + 3
+	expected := make([]Root, end+1)
+	expected[0] = chain.StateRoot()
+	for slot := Slot(1); slot <= end; slot++ {
+		if slot%3 == 0 || slot == end {
+			block := produceTestBlock(t, chain, keys, slot)
+			if err := arch.AddBlock(block, chain); err != nil {
+				t.Fatal(err)
+			}
+			if err := arch.AddBlock(block, chain); err == nil {
+				t.Fatal("expected duplicate block to be rejected")
+			}
+			expected[slot] = chain.StateRoot()
+		} else {
+			state := copyState(t, chain)
+			state.ProcessSlots(slot)
+			expected[slot] = state.StateRoot()
+		}
+	}
+
+	// regenerate every state of the first epochs, including the first snapshot, and after that only epoch starts
+	for slot := Slot(0); slot <= end; slot++ {
+		if slot > SLOTS_PER_EPOCH*3 && slot%SLOTS_PER_EPOCH != 0 && slot != end {
+			continue
+		}
+		state, err := arch.StateAt(slot)
+		if err != nil {
+			t.Fatalf("slot %d: %v", slot, err)
+		}
+		if root := state.StateRoot(); root != expected[slot] {
+			t.Fatalf("slot %d: regenerated state root %x, expected %x", slot, root, expected[slot])
+		}
+	}
+	if _, err := arch.StateAt(end + 1); err != archive.ErrFutureSlot {
+		t.Errorf("expected future slot error, got %v", err)
+	}
+
+	for slot := Slot(0); slot < SLOTS_PER_HISTORICAL_ROOT*2; slot++ {
+		proof, err := arch.ProveBlockRoot(slot)
+		if err != nil {
+			t.Fatalf("slot %d: %v", slot, err)
+		}
+		if root, err := arch.BlockRootAt(slot); err != nil || root != proof.BlockRoot {
+			t.Fatalf("slot %d: proven block root %x, expected %x (%v)", slot, proof.BlockRoot, root, err)
+		}
+		if !proof.Verify(chain.HistoricalRoots) {
+			t.Fatalf("slot %d: invalid block root proof", slot)
+		}
+		proof.BlockRoot[0] ^= 1
+		if proof.Verify(chain.HistoricalRoots) {
+			t.Fatalf("slot %d: expected proof of different block root to be invalid", slot)
+		}
+	}
+	// the current batch is not in the historical roots yet
+	if _, err := arch.ProveBlockRoot(SLOTS_PER_HISTORICAL_ROOT * 2); err == nil {
+		t.Error("expected proof of slot in the current batch to fail")
+	}
+
+	// the archive can serve the chain to sync from
+	blocks, err := arch.BlocksByRange(SLOTS_PER_EPOCH, uint64(SLOTS_PER_EPOCH))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		if s := b.Message.Slot; s < SLOTS_PER_EPOCH || s >= SLOTS_PER_EPOCH*2 || s%3 != 0 {
+			t.Errorf("unexpected block at slot %d", s)
+		}
+	}
+	if b, err := arch.BlockByRoot(arch.HeadRoot()); err != nil || b.Message.Slot != end {
+		t.Errorf("expected head block at slot %d, got %v", end, err)
+	}
+}
@@ -0,0 +1,144 @@
+package benches
+
+import (
+	"errors"
+	"github.com/phoreproject/bls/g1pubs"
+	"github.com/protolambda/zrnt/eth2/beacon/randao"
+	"github.com/protolambda/zrnt/eth2/chainsync"
+	. "github.com/protolambda/zrnt/eth2/core"
+	. "github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"testing"
+)
+
+func createKeyedTestState(t testing.TB, count uint64) (*FullFeaturedState, [][32]byte) {
+	keys := make([][32]byte, count)
+	validators := CreateTestValidators(count, MAX_EFFECTIVE_BALANCE)
+	for i := range keys {
+		keys[i] = [32]byte{30: byte((i + 1) >> 8), 31: byte(i + 1)}
+		validators[i].Pubkey = g1pubs.PrivToPub(g1pubs.DeserializeSecretKey(keys[i])).Serialize()
+	}
+	state, err := KickStartState(Root{123}, 1564000000, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, keys
+}
+
+// Produces an empty signed block at the given slot on top of the state, and applies it to the state.
+func produceTestBlock(t testing.TB, state *FullFeaturedState, keys [][32]byte, slot Slot) *SignedBeaconBlock {
+	pre := copyState(t, state)
+	pre.ProcessSlots(slot)
+	proposer := pre.GetBeaconProposerIndex(slot)
+	epoch := slot.ToEpoch()
+	block := &SignedBeaconBlock{}
+	block.Message.Slot = slot
+	block.Message.ParentRoot = pre.HeadRoot()
+	block.Message.Body.RandaoReveal = bls.BlsSign(keys[proposer],
+		ssz.HashTreeRoot(epoch, randao.RandaoEpochSSZ), pre.GetDomain(DOMAIN_RANDAO, epoch))
+	block.Message.Body.Eth1Data = pre.Eth1Data
+	if err := (&BlockProcessFeature{Block: block, Meta: pre}).Process(); err != nil {
+		t.Fatal(err)
+	}
+	block.Message.StateRoot = pre.StateRoot()
+	block.Signature = bls.BlsSign(keys[proposer],
+		ssz.HashTreeRoot(&block.Message, BeaconBlockSSZ), pre.GetDomain(DOMAIN_BEACON_PROPOSER, epoch))
+	if err := state.StateTransition(&BlockProcessFeature{Block: block, Meta: state}, true); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+type flakySource struct {
+	chainsync.BlockSource
+	failures int
+}
+
+func (s *flakySource) BlocksByRange(start Slot, count uint64) ([]*SignedBeaconBlock, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("peer disconnected")
+	}
+	return s.BlockSource.BlocksByRange(start, count)
+}
+
+func TestRangeSync(t *testing.T) {
+	genesis, keys := createKeyedTestState(t, uint64(SLOTS_PER_EPOCH)*2)
+	chain := copyState(t, genesis)
+	source := chainsync.NewMemoryBlockSource()
+	var blocks []*SignedBeaconBlock
+	target := Slot(SLOTS_PER_EPOCH) + 3
+	for slot := Slot(1); slot <= target; slot++ {
+		// skip a few slots
+		if slot%5 == 2 {
+			continue
+		}
+		b := produceTestBlock(t, chain, keys, slot)
+		source.Add(b)
+		blocks = append(blocks, b)
+	}
+	expected := chain.StateRoot()
+
+	t.Run("range", func(t *testing.T) {
+		flaky := &flakySource{BlockSource: source, failures: 2}
+		s := chainsync.NewRangeSync(copyState(t, genesis), flaky)
+		s.BatchSize = 3
+		if err := s.SyncTo(target); err != nil {
+			t.Fatal(err)
+		}
+		if s.State.StateRoot() != expected {
+			t.Error("synced state does not match chain")
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		s := chainsync.NewRangeSync(copyState(t, genesis), source)
+		head := ssz.HashTreeRoot(&blocks[len(blocks)-1].Message, BeaconBlockSSZ)
+		if err := s.SyncToRoot(head); err != nil {
+			t.Fatal(err)
+		}
+		if s.State.StateRoot() != expected {
+			t.Error("synced state does not match chain")
+		}
+	})
+
+	t.Run("invalid batch", func(t *testing.T) {
+		bad := *blocks[3]
+		bad.Message.StateRoot = Root{1}
+		corrupt := chainsync.NewMemoryBlockSource(blocks...)
+		corrupt.Add(&bad)
+		s := chainsync.NewRangeSync(copyState(t, genesis), corrupt)
+		s.BatchSize = 4
+		s.MaxRetries = 1
+		if err := s.SyncTo(target); err == nil {
+			t.Fatal("expected sync to fail on corrupt batch")
+		}
+		// the first batch is fine, the second batch contains the corrupt block
+		valid := chainsync.NewRangeSync(copyState(t, genesis), source)
+		if err := valid.SyncTo(4); err != nil {
+			t.Fatal(err)
+		}
+		if s.State.StateRoot() != valid.State.StateRoot() {
+			t.Error("expected state to be rolled back to before the corrupt batch")
+		}
+	})
+
+	t.Run("invalid batch from peer", func(t *testing.T) {
+		bad := *blocks[3]
+		bad.Message.StateRoot = Root{1}
+		corrupt := chainsync.NewMemoryBlockSource(blocks...)
+		corrupt.Add(&bad)
+		s := chainsync.NewRangeSync(copyState(t, genesis), corrupt)
+		// the corrupt batch is requested again from the next peer
+		s.Peers = []chainsync.BlockSource{&flakySource{BlockSource: source, failures: 1}, source}
+		s.BatchSize = 4
+		s.MaxRetries = 2
+		if err := s.SyncTo(target); err != nil {
+			t.Fatal(err)
+		}
+		if s.State.StateRoot() != expected {
+			t.Error("synced state does not match chain")
+		}
+	})
+}
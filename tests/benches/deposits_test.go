@@ -0,0 +1,100 @@
+package benches
+
+import (
+	"bytes"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDepositDataTooling(t *testing.T) {
+	withdrawalPub := bls.BlsSecretToPubkey([32]byte{31: 0x77})
+	creds := BlsWithdrawalCredentials(withdrawalPub)
+	if h := hashing.Hash(withdrawalPub[:]); creds[0] != BLS_WITHDRAWAL_PREFIX || !bytes.Equal(creds[1:], h[1:]) {
+		t.Fatalf("unexpected withdrawal credentials: %x", creds)
+	}
+
+	var valid []*DepositData
+	for i := byte(1); i <= 3; i++ {
+		d := NewDepositData([32]byte{31: i}, withdrawalPub, MAX_EFFECTIVE_BALANCE)
+		if err := d.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		valid = append(valid, d)
+	}
+
+	lowAmount := NewDepositData([32]byte{31: 4}, withdrawalPub, MIN_DEPOSIT_AMOUNT-1)
+	highAmount := NewDepositData([32]byte{31: 5}, withdrawalPub, MAX_EFFECTIVE_BALANCE+1)
+	badSig := NewDepositData([32]byte{31: 6}, withdrawalPub, MAX_EFFECTIVE_BALANCE)
+	badSig.Signature = valid[0].Signature
+	badPrefix := NewDepositData([32]byte{31: 7}, withdrawalPub, MAX_EFFECTIVE_BALANCE)
+	badPrefix.WithdrawalCredentials[0] = 0xff
+	invalid := map[string]*DepositData{"low amount": lowAmount, "high amount": highAmount, "bad prefix": badPrefix}
+	if bls.BLS_ACTIVE {
+		invalid["bad signature"] = badSig
+	}
+	for name, d := range invalid {
+		if err := d.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "deposits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile := func(name string, deposits []*DepositData) string {
+		var buf bytes.Buffer
+		if err := WriteLaunchpadJSON(&buf, deposits); err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	validFile := writeFile("valid.json", valid)
+	invalidFile := writeFile("invalid.json", []*DepositData{valid[0], badPrefix, highAmount})
+
+	// round trip
+	f, err := os.Open(validFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ReadLaunchpadJSON(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range valid {
+		if *decoded[i] != *valid[i] {
+			t.Errorf("deposit %d changed in JSON round trip", i)
+		}
+	}
+
+	if errs := ValidateLaunchpadFiles(validFile); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	errs := ValidateLaunchpadFiles(validFile, invalidFile, filepath.Join(dir, "missing.json"))
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got: %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "invalid.json: deposit 1") || !strings.Contains(errs[1].Error(), "invalid.json: deposit 2") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	// the roots in the file have to match the deposit data
+	l := valid[0].Launchpad()
+	l.Amount -= 1
+	if _, err := l.DepositData(); err == nil {
+		t.Error("expected mismatching deposit message root to fail")
+	}
+}
@@ -0,0 +1,69 @@
+package benches
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	. "github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"github.com/protolambda/zrnt/tests/spec/test_util"
+	"testing"
+)
+
+func TestDiffTransition(t *testing.T) {
+	state, keys := createKeyedTestState(t, 16)
+	pre := copyState(t, state)
+	blocks := []*SignedBeaconBlock{
+		produceTestBlock(t, state, keys, 1),
+		produceTestBlock(t, state, keys, 3),
+	}
+	expected := copyState(t, state).BeaconState
+	transition := test_util.BlocksTransition(blocks...)
+
+	if d, err := test_util.DiffTransition(pre.BeaconState, expected, transition); err != nil {
+		t.Fatal(err)
+	} else if d != nil {
+		t.Fatalf("unexpected divergence: %s", d)
+	}
+
+	// Divergences later in the state are not reported, only the first one.
+	expected.Slashings[2] += 1
+	expected.Balances[5] += 1
+	expected.Validators[3].EffectiveBalance -= EFFECTIVE_BALANCE_INCREMENT
+	d, err := test_util.DiffTransition(pre.BeaconState, expected, transition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d == nil {
+		t.Fatal("expected a divergence")
+	}
+	if d.Path != "Validators[3].EffectiveBalance" {
+		t.Errorf("unexpected divergence path: %s", d.Path)
+	}
+	if d.Got != MAX_EFFECTIVE_BALANCE || d.Expected != MAX_EFFECTIVE_BALANCE-EFFECTIVE_BALANCE_INCREMENT {
+		t.Errorf("unexpected divergence values: %s", d)
+	}
+
+	// Missing elements are reported as well.
+	expected = copyState(t, state).BeaconState
+	expected.Balances = expected.Balances[:10]
+	if d, err := test_util.DiffTransition(pre.BeaconState, expected, transition); err != nil {
+		t.Fatal(err)
+	} else if d == nil || d.Path != "Balances[10]" || d.Expected != nil {
+		t.Errorf("unexpected divergence: %v", d)
+	}
+
+	// The blocks are still applied if their state roots do not match the computed state,
+	// and the divergence is reported instead of the state root mismatch.
+	expected = copyState(t, state).BeaconState
+	expected.Balances[5] += 1
+	last := *blocks[1]
+	last.Message.StateRoot = expected.StateRoot()
+	last.Signature = bls.BlsSign(keys[state.GetBeaconProposerIndex(last.Message.Slot)],
+		ssz.HashTreeRoot(&last.Message, BeaconBlockSSZ), state.GetDomain(DOMAIN_BEACON_PROPOSER, last.Message.Slot.ToEpoch()))
+	transition = test_util.BlocksTransition(blocks[0], &last)
+	if d, err := test_util.DiffTransition(pre.BeaconState, expected, transition); err != nil {
+		t.Fatal(err)
+	} else if d == nil || d.Path != "Balances[5]" || d.Got != expected.Balances[5]-1 {
+		t.Errorf("unexpected divergence: %v", d)
+	}
+}
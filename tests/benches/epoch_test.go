@@ -0,0 +1,204 @@
+package benches
+
+import (
+	"bytes"
+	"github.com/protolambda/zrnt/eth2/beacon/attestations"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zssz"
+	"math/rand"
+	"testing"
+)
+
+// Creates a state at the end of an epoch, with randomized balances and pending attestations,
+// ready to run the epoch transition with.
+func CreateEpochTestState(validatorCount uint64, rng *rand.Rand) *phase0.FullFeaturedState {
+	state := CreateTestState(validatorCount, MAX_EFFECTIVE_BALANCE)
+	state.ProcessSlots((GENESIS_EPOCH + 3).GetStartSlot() - 1)
+
+	for i := range state.Balances {
+		state.Balances[i] = MAX_EFFECTIVE_BALANCE - Gwei(rng.Intn(int(EFFECTIVE_BALANCE_INCREMENT*4))) + EFFECTIVE_BALANCE_INCREMENT
+	}
+	for i := 0; i < len(state.Validators)/10; i++ {
+		state.Validators[rng.Intn(len(state.Validators))].Slashed = true
+	}
+
+	currentEpoch := state.CurrentEpoch()
+	randomRoot := func(expected Root) Root {
+		if rng.Intn(4) == 0 {
+			return Root{0xff, byte(rng.Intn(256))}
+		}
+		return expected
+	}
+	for epoch := state.PreviousEpoch(); epoch <= currentEpoch; epoch++ {
+		for slot := epoch.GetStartSlot(); slot < (epoch+1).GetStartSlot() && slot < state.Slot; slot++ {
+			committeeCount := state.GetCommitteeCountAtSlot(slot)
+			for index := CommitteeIndex(0); index < CommitteeIndex(committeeCount); index++ {
+				committee := state.GetBeaconCommittee(slot, index)
+				// multiple attestations for the same committee, to cover inclusion order
+				for j := 0; j < 3; j++ {
+					bits := make(attestations.CommitteeBits, (len(committee)/8)+1)
+					for k := range committee {
+						bits.SetBit(uint64(k), rng.Intn(3) != 0)
+					}
+					bits.SetBit(uint64(len(committee)), true)
+					att := &attestations.PendingAttestation{
+						AggregationBits: bits,
+						Data: attestations.AttestationData{
+							Slot:            slot,
+							Index:           index,
+							BeaconBlockRoot: randomRoot(state.GetBlockRootAtSlot(slot)),
+							Source:          state.CurrentJustifiedCheckpoint,
+							Target: Checkpoint{
+								Epoch: epoch,
+								Root:  randomRoot(state.GetBlockRoot(epoch)),
+							},
+						},
+						InclusionDelay: MIN_ATTESTATION_INCLUSION_DELAY + Slot(rng.Intn(int(SLOTS_PER_EPOCH))),
+						ProposerIndex:  ValidatorIndex(rng.Intn(len(state.Validators))),
+					}
+					if epoch == currentEpoch {
+						state.CurrentEpochAttestations = append(state.CurrentEpochAttestations, att)
+					} else {
+						state.PreviousEpochAttestations = append(state.PreviousEpochAttestations, att)
+					}
+				}
+			}
+		}
+	}
+	return state
+}
+
+func copyState(t testing.TB, state *phase0.FullFeaturedState) *phase0.FullFeaturedState {
+	var buf bytes.Buffer
+	if _, err := zssz.Encode(&buf, state.BeaconState, phase0.BeaconStateSSZ); err != nil {
+		t.Fatal(err)
+	}
+	out := new(phase0.BeaconState)
+	if err := zssz.Decode(&buf, uint64(buf.Len()), out, phase0.BeaconStateSSZ); err != nil {
+		t.Fatal(err)
+	}
+	full := phase0.NewFullFeaturedState(out)
+	full.LoadPrecomputedData()
+	return full
+}
+
+func TestParallelEpochProcessing(t *testing.T) {
+	rng := rand.New(rand.NewSource(1234))
+	state := CreateEpochTestState(1000, rng)
+	expected := copyState(t, state)
+	expected.ProcessEpoch()
+	expectedRoot := expected.StateRoot()
+	for _, workers := range []int{2, 3, 8} {
+		result := copyState(t, state)
+		result.Concurrency = workers
+		result.ProcessEpoch()
+		if root := result.StateRoot(); root != expectedRoot {
+			t.Errorf("epoch processing with %d workers has different post-state root: %x <> %x", workers, root, expectedRoot)
+		}
+	}
+}
+
+func TestRewardsReport(t *testing.T) {
+	rng := rand.New(rand.NewSource(1234))
+	state := CreateEpochTestState(1000, rng)
+	expected := state.AttestationDeltas()
+
+	state.Rewards = new(RewardsReport)
+	deltas := state.AttestationDeltas()
+	total := state.Rewards.Total()
+	for i := range expected.Rewards {
+		if deltas.Rewards[i] != expected.Rewards[i] || deltas.Penalties[i] != expected.Penalties[i] {
+			t.Fatalf("deltas of validator %d changed when reporting", i)
+		}
+		if total.Rewards[i] != expected.Rewards[i] || total.Penalties[i] != expected.Penalties[i] {
+			t.Fatalf("report components of validator %d do not add up to deltas: %d/%d <> %d/%d", i,
+				total.Rewards[i], total.Penalties[i], expected.Rewards[i], expected.Penalties[i])
+		}
+	}
+}
+
+func TestRewardsReportBalances(t *testing.T) {
+	rng := rand.New(rand.NewSource(1234))
+	state := CreateEpochTestState(1000, rng)
+	// nearly empty balances, to clip penalties at zero
+	for i := 0; i < len(state.Balances); i += 7 {
+		state.Balances[i] = 1
+	}
+	check := func(name string, pre []Gwei) {
+		t.Helper()
+		total := state.Rewards.Total()
+		total.Grow(uint64(len(state.Balances)))
+		for i, post := range state.Balances {
+			if pre[i]+total.Rewards[i] != post+total.Penalties[i] {
+				t.Fatalf("%s: report of validator %d does not match balance change: %d + %d - %d <> %d",
+					name, i, pre[i], total.Rewards[i], total.Penalties[i], post)
+			}
+		}
+	}
+
+	state.Rewards = new(RewardsReport)
+	pre := append([]Gwei(nil), state.Balances...)
+	state.ProcessEpochRewardsAndPenalties()
+	check("epoch rewards and penalties", pre)
+
+	zeroed := 0
+	for _, b := range state.Balances {
+		if b == 0 {
+			zeroed++
+		}
+	}
+	if zeroed == 0 {
+		t.Fatal("expected some penalties to be clipped")
+	}
+
+	state.Rewards = new(RewardsReport)
+	pre = append([]Gwei(nil), state.Balances...)
+	state.SlashValidator(7, nil)
+	check("slashing", pre)
+}
+
+func TestRewardsReportCopy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1234))
+	state := CreateEpochTestState(100, rng)
+	state.Rewards = new(RewardsReport)
+	state.AttestationDeltas()
+	before := state.Rewards.Total()
+
+	dup, err := state.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dup.Rewards == state.Rewards {
+		t.Fatal("copy shares the rewards report")
+	}
+	dup.AttestationDeltas()
+	after := state.Rewards.Total()
+	for i := range before.Rewards {
+		if after.Rewards[i] != before.Rewards[i] || after.Penalties[i] != before.Penalties[i] {
+			t.Fatalf("report of validator %d changed by processing the copy", i)
+		}
+	}
+}
+
+func benchmarkEpochProcessing(b *testing.B, workers int) {
+	rng := rand.New(rand.NewSource(1234))
+	state := CreateEpochTestState(10000, rng)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s := copyState(b, state)
+		s.Concurrency = workers
+		b.StartTimer()
+		s.ProcessEpoch()
+	}
+}
+
+func BenchmarkEpochProcessing(b *testing.B) {
+	benchmarkEpochProcessing(b, 1)
+}
+
+func BenchmarkEpochProcessingParallel(b *testing.B) {
+	benchmarkEpochProcessing(b, 4)
+}
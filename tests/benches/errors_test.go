@@ -0,0 +1,90 @@
+package benches
+
+import (
+	"errors"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	. "github.com/protolambda/zrnt/eth2/beacon/exits"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/attslash"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/propslash"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"testing"
+)
+
+func TestOperationErrors(t *testing.T) {
+	state := CreateTestState(100, MAX_EFFECTIVE_BALANCE)
+
+	err := state.ProcessVoluntaryExits([]SignedVoluntaryExit{{Message: VoluntaryExit{ValidatorIndex: 1000}}})
+	var opErr *OperationError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected operation error, got: %v", err)
+	}
+	if opErr.Kind != VoluntaryExitOperation || opErr.Index != 0 || opErr.Reason != ExitInvalidIndex {
+		t.Errorf("unexpected operation error: %v", opErr)
+	}
+	if !errors.Is(err, ExitInvalidIndex) || errors.Is(err, ExitTooSoon) {
+		t.Errorf("unexpected reason match: %v", err)
+	}
+
+	// double vote, but the indices of the attestations are not sorted
+	slashing := AttesterSlashing{
+		Attestation1: IndexedAttestation{AttestingIndices: []ValidatorIndex{2, 1}},
+		Attestation2: IndexedAttestation{AttestingIndices: []ValidatorIndex{2, 1}},
+	}
+	slashing.Attestation2.Data.BeaconBlockRoot = Root{1}
+	err = state.ProcessAttesterSlashings([]AttesterSlashing{slashing})
+	if !errors.Is(err, AttesterSlashingInvalidAttestation) {
+		t.Errorf("expected invalid attestation reason, got: %v", err)
+	}
+	if !errors.Is(err, IndexedAttestationUnsorted) {
+		t.Errorf("expected wrapped unsorted indices reason, got: %v", err)
+	}
+}
+
+func TestValidateOperations(t *testing.T) {
+	state := CreateTestState(100, MAX_EFFECTIVE_BALANCE)
+	preRoot := state.StateRoot()
+
+	exit := &SignedVoluntaryExit{Message: VoluntaryExit{ValidatorIndex: 1}}
+	if err := state.ValidateVoluntaryExit(exit); !errors.Is(err, ExitTooSoon) {
+		t.Errorf("expected exit to be too soon, got: %v", err)
+	}
+	if err := state.ProcessVoluntaryExit(exit); !errors.Is(err, ExitTooSoon) {
+		t.Errorf("expected processing to fail the same as validation, got: %v", err)
+	}
+
+	att := &Attestation{}
+	if err := state.ValidateAttestation(att); !errors.Is(err, AttestationSlotTooNew) {
+		t.Errorf("expected attestation to be too new, got: %v", err)
+	}
+
+	dep := &Deposit{Data: DepositData{Amount: MAX_EFFECTIVE_BALANCE}}
+	if err := state.ValidateDeposit(dep); !errors.Is(err, DepositInvalidProof) {
+		t.Errorf("expected deposit proof to be invalid, got: %v", err)
+	}
+
+	ps := &ProposerSlashing{ProposerIndex: 1}
+	ps.SignedHeader2.Message.Slot = 1
+	if err := state.ValidateProposerSlashing(ps); !errors.Is(err, ProposerSlashingSlotMismatch) {
+		t.Errorf("expected proposer slashing slots to mismatch, got: %v", err)
+	}
+	ps.SignedHeader1.Message.Slot = 1
+	if err := state.ValidateProposerSlashing(ps); !errors.Is(err, ProposerSlashingSameHeaders) {
+		t.Errorf("expected proposer slashing headers to be the same, got: %v", err)
+	}
+
+	as := &AttesterSlashing{}
+	if err := state.ValidateAttesterSlashing(as); !errors.Is(err, AttesterSlashingNotSlashable) {
+		t.Errorf("expected attester slashing to not be slashable, got: %v", err)
+	}
+	as.Attestation2.Data.BeaconBlockRoot = Root{1}
+	as.Attestation1.AttestingIndices = CommitteeIndices{2, 1}
+	if err := state.ValidateAttesterSlashing(as); !errors.Is(err, AttesterSlashingInvalidAttestation) ||
+		!errors.Is(err, IndexedAttestationUnsorted) {
+		t.Errorf("expected attester slashing attestation to be unsorted, got: %v", err)
+	}
+
+	if postRoot := state.StateRoot(); postRoot != preRoot {
+		t.Errorf("validation changed the state: %x <> %x", postRoot, preRoot)
+	}
+}
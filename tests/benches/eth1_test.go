@@ -23,3 +23,67 @@ func BenchmarkEth1Voting(b *testing.B) {
 		}
 	}
 }
+
+func TestEth1Vote(t *testing.T) {
+	state := CreateTestState(uint64(SLOTS_PER_EPOCH)*4, MAX_EFFECTIVE_BALANCE)
+	state.GenesisTime = 1564000000
+	state.Slot = SLOTS_PER_ETH1_VOTING_PERIOD*10 + 3
+	periodStart := VotingPeriodStartTime(state.GenesisTime, state.Slot)
+
+	chain := new(MemoryEth1Chain)
+	var candidates []*Eth1Block
+	for i := uint64(0); i < 4*ETH1_FOLLOW_DISTANCE; i++ {
+		b := &Eth1Block{
+			Hash:         Root{byte(i >> 8), byte(i)},
+			Number:       i,
+			Timestamp:    periodStart - Timestamp(4*ETH1_FOLLOW_DISTANCE-i)*SECONDS_PER_ETH1_BLOCK,
+			DepositRoot:  Root{0xd, byte(i >> 8), byte(i)},
+			DepositCount: DepositIndex(i),
+		}
+		chain.AddBlock(b)
+		if IsCandidateBlock(b, periodStart) {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) < 4 {
+		t.Fatalf("expected more candidates, got %d", len(candidates))
+	}
+
+	check := func(name string, expected Eth1Data) {
+		vote, err := state.GetEth1Vote(chain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vote != expected {
+			t.Errorf("%s: unexpected vote: %v, expected %v", name, vote, expected)
+		}
+	}
+
+	// without votes, the latest candidate is the default
+	check("default", candidates[len(candidates)-1].Eth1Data())
+
+	// votes for blocks outside of the window, or with a lower deposit count, are ignored
+	state.Eth1Data = candidates[1].Eth1Data()
+	state.Eth1DataVotes = append(state.Eth1DataVotes,
+		chain.Blocks[len(chain.Blocks)-1].Eth1Data(),
+		chain.Blocks[len(chain.Blocks)-1].Eth1Data(),
+		candidates[0].Eth1Data(),
+		candidates[0].Eth1Data(),
+		candidates[3].Eth1Data())
+	check("single valid vote", candidates[3].Eth1Data())
+
+	// the majority wins, and the earliest vote wins ties
+	state.Eth1DataVotes = append(state.Eth1DataVotes,
+		candidates[2].Eth1Data(),
+		candidates[2].Eth1Data(),
+		candidates[3].Eth1Data())
+	check("tie", candidates[3].Eth1Data())
+	state.Eth1DataVotes = append(state.Eth1DataVotes, candidates[2].Eth1Data())
+	check("majority", candidates[2].Eth1Data())
+
+	// without candidates, the current eth1 data is kept
+	current := state.Eth1Data
+	state.Eth1DataVotes = nil
+	chain = new(MemoryEth1Chain)
+	check("no candidates", current)
+}
@@ -0,0 +1,96 @@
+package benches
+
+import (
+	"bytes"
+	"crypto/sha256"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/eth1follow"
+	. "github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"testing"
+)
+
+func createTestDepositData(i uint64, amount Gwei) DepositData {
+	key := [32]byte{29: 0xde, 30: byte((i + 1) >> 8), 31: byte(i + 1)}
+	return *NewDepositData(key, bls.BlsSecretToPubkey(key), amount)
+}
+
+// Creates a chain of eth1 log blocks, with a deposit in every other block.
+func createTestLogBlocks(count uint64, startTime Timestamp) (out []*eth1follow.LogBlock) {
+	deposits := DepositIndex(0)
+	for i := uint64(0); i < count; i++ {
+		b := &eth1follow.LogBlock{
+			Hash:      sha256.Sum256([]byte{byte(i >> 8), byte(i)}),
+			Number:    i,
+			Timestamp: startTime + Timestamp(i)*SECONDS_PER_ETH1_BLOCK,
+		}
+		if i%2 == 1 {
+			b.Logs = append(b.Logs, eth1follow.DepositLog{Index: deposits, Data: createTestDepositData(uint64(deposits), MAX_EFFECTIVE_BALANCE)})
+			deposits++
+		}
+		out = append(out, b)
+	}
+	return
+}
+
+func TestDepositFollower(t *testing.T) {
+	blocks := createTestLogBlocks(uint64(SLOTS_PER_EPOCH)*4, 1564000000)
+	var buf bytes.Buffer
+	for _, b := range blocks {
+		if err := eth1follow.WriteLogBlock(&buf, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := eth1follow.NewDepositFollower(eth1follow.NewFileLogSource(&buf))
+	if n, err := f.Follow(); err != nil {
+		t.Fatal(err)
+	} else if n != len(blocks) {
+		t.Fatalf("expected %d blocks, followed %d", len(blocks), n)
+	}
+
+	// the incremental deposit roots match the SSZ deposit list roots
+	var roots DepositRoots
+	for i, b := range f.Chain.Blocks {
+		for _, l := range blocks[i].Logs {
+			roots = append(roots, ssz.HashTreeRoot(&l.Data, DepositDataSSZ))
+		}
+		if expected := ssz.HashTreeRoot(&roots, DepositRootsSSZ); b.DepositRoot != expected {
+			t.Fatalf("block %d: deposit root %x does not match %x", i, b.DepositRoot, expected)
+		}
+		if b.DepositCount != DepositIndex(len(roots)) {
+			t.Fatalf("block %d: unexpected deposit count %d", i, b.DepositCount)
+		}
+	}
+
+	// blocks have to be processed in order
+	if err := f.ProcessBlock(blocks[3]); err == nil {
+		t.Fatal("expected out of order block to fail")
+	}
+
+	// genesis from the deposits up to the middle of the chain, with verified deposits
+	mid := f.Chain.Blocks[len(blocks)/2]
+	state, err := f.GenesisState(mid.Hash, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.DepositIndex != mid.DepositCount || state.Eth1Data != mid.Eth1Data() {
+		t.Fatalf("unexpected genesis eth1 state: %v, deposit index %d", state.Eth1Data, state.DepositIndex)
+	}
+
+	// the remaining deposits can be included in blocks, proven against the latest deposit root
+	state.Eth1Data = f.Head().Eth1Data()
+	for state.DepositIndex < state.Eth1Data.DepositCount {
+		deps, err := f.BlockDeposits(state.Eth1Data, state.DepositIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := state.ProcessDeposits(deps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if uint64(len(state.Validators)) != uint64(f.Head().DepositCount) {
+		t.Fatalf("expected %d validators, got %d", f.Head().DepositCount, len(state.Validators))
+	}
+}
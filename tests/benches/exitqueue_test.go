@@ -0,0 +1,64 @@
+package benches
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"testing"
+)
+
+func checkExitQueueData(t *testing.T, state *phase0.FullFeaturedState) {
+	t.Helper()
+	epoch := state.CurrentEpoch()
+	for _, e := range []Epoch{epoch, epoch + 1, epoch + 10} {
+		if a, b := state.GetChurnLimit(e), state.ValidatorsState.GetChurnLimit(e); a != b {
+			t.Fatalf("epoch %d: churn limit of epoch %d is %d, expected %d", epoch, e, a, b)
+		}
+	}
+	if a, b := state.ExitQueueEnd(epoch), state.ValidatorsState.ExitQueueEnd(epoch); a != b {
+		t.Fatalf("epoch %d: cached exit queue end %d, expected %d", epoch, a, b)
+	}
+}
+
+func TestExitQueueData(t *testing.T) {
+	state := CreateTestState(uint64(SLOTS_PER_EPOCH)*8, MAX_EFFECTIVE_BALANCE)
+	checkExitQueueData(t, state)
+
+	churnLimit := state.GetChurnLimit(state.CurrentEpoch())
+	next := ValidatorIndex(0)
+	exit := func(n uint64) {
+		for i := uint64(0); i < n; i++ {
+			// the exit epoch as computed by scanning the registry
+			expected := state.ValidatorsState.ExitQueueEnd(state.CurrentEpoch())
+			// the registry state itself initiates exits without the cache
+			scanned := copyState(t, state)
+			scanned.RegistryState.InitiateValidatorExit(state.CurrentEpoch(), next)
+			state.InitiateValidatorExit(state.CurrentEpoch(), next)
+			if v := state.Validators[next]; v.ExitEpoch != expected || v.WithdrawableEpoch != expected+MIN_VALIDATOR_WITHDRAWABILITY_DELAY {
+				t.Fatalf("validator %d: exit epoch %d (withdrawable %d), expected %d", next, v.ExitEpoch, v.WithdrawableEpoch, expected)
+			}
+			if *scanned.Validators[next] != *state.Validators[next] {
+				t.Fatalf("validator %d: exit of registry state does not match the cached exit", next)
+			}
+			checkExitQueueData(t, state)
+			next++
+		}
+	}
+	// fill multiple epochs of the exit queue at once
+	exit(churnLimit*3 + 1)
+	// exits of validators that exited already are ignored
+	state.InitiateValidatorExit(state.CurrentEpoch(), 0)
+	checkExitQueueData(t, state)
+
+	for i := 0; i < 4; i++ {
+		state.ProcessSlots((state.CurrentEpoch() + 1).GetStartSlot())
+		checkExitQueueData(t, state)
+		exit(churnLimit + 1)
+	}
+}
+
+func BenchmarkExitQueueEnd(b *testing.B) {
+	state := CreateTestState(10000, MAX_EFFECTIVE_BALANCE)
+	for i := 0; i < b.N; i++ {
+		state.ExitQueueEnd(state.CurrentEpoch())
+	}
+}
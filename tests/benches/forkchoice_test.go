@@ -0,0 +1,68 @@
+package benches
+
+import (
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/sim"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"sort"
+	"testing"
+)
+
+func TestForkChoice(t *testing.T) {
+	cfg := sim.Config{
+		ValidatorCount: uint64(SLOTS_PER_EPOCH) * 4,
+		Seed:           3,
+		GenesisTime:    1564000000,
+		Participation:  1,
+		ForkRate:       0.3,
+	}
+	s := runSim(t, cfg, 4)
+	if len(s.Forks) == 0 {
+		t.Fatal("expected forks")
+	}
+	genesis, err := sim.NewSimulator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := forkchoice.NewStore(genesis.State)
+
+	blocks := append(append([]*phase0.SignedBeaconBlock{}, s.Blocks...), s.Forks...)
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].Message.Slot < blocks[j].Message.Slot
+	})
+	for _, b := range blocks {
+		store.OnTick(cfg.GenesisTime + Timestamp(b.Message.Slot)*SECONDS_PER_SLOT)
+		if err := store.OnBlock(b); err != nil {
+			t.Fatalf("block at slot %d: %v", b.Message.Slot, err)
+		}
+		for i := range b.Message.Body.Attestations {
+			if err := store.OnAttestation(&b.Message.Body.Attestations[i]); err != nil {
+				t.Fatalf("attestation in block at slot %d: %v", b.Message.Slot, err)
+			}
+		}
+	}
+	store.OnTick(cfg.GenesisTime + Timestamp(s.State.Slot+1)*SECONDS_PER_SLOT)
+
+	// Attestations are only included in canonical blocks, the forks have no weight.
+	// The last canonical block may not have votes yet, check its parent.
+	head, err := store.GetHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := &s.Blocks[len(s.Blocks)-2].Message
+	if store.GetAncestor(head, parent.Slot) != ssz.HashTreeRoot(parent, phase0.BeaconBlockSSZ) {
+		t.Error("head does not build on the canonical chain")
+	}
+	if store.FinalizedCheckpoint != s.State.FinalizedCheckpoint {
+		t.Errorf("expected finalized checkpoint %v, got %v", s.State.FinalizedCheckpoint, store.FinalizedCheckpoint)
+	}
+	if store.JustifiedCheckpoint != s.State.CurrentJustifiedCheckpoint {
+		t.Errorf("expected justified checkpoint %v, got %v", s.State.CurrentJustifiedCheckpoint, store.JustifiedCheckpoint)
+	}
+
+	if err := store.OnBlock(s.Blocks[0]); err != forkchoice.ErrNotFinalized {
+		t.Errorf("expected block before finality to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package benches
+
+import (
+	"errors"
+	"github.com/protolambda/zrnt/eth2/archive"
+	"github.com/protolambda/zrnt/eth2/chainsync"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/forks"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/sim"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"testing"
+)
+
+func TestForkUpgrade(t *testing.T) {
+	genesis, keys := createKeyedTestState(t, uint64(SLOTS_PER_EPOCH)*2)
+	next := &forks.Fork{Name: "next", Epoch: 1, Version: Version{1}, Family: forks.Phase0Family{}}
+	reg, err := forks.NewRegistry(forks.Phase0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register(next); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register(&forks.Fork{Name: "old", Epoch: 1, Family: forks.Phase0Family{}}); !errors.Is(err, forks.ErrForkOrder) {
+		t.Errorf("expected fork order error, got %v", err)
+	}
+	if reg.ForkAt(0) != forks.Phase0 || reg.ForkAt(5) != next {
+		t.Error("unexpected fork lookup")
+	}
+
+	forkSlot := next.Epoch.GetStartSlot()
+	pre := forks.Phase0State{FullFeaturedState: copyState(t, genesis)}
+	post, err := reg.ProcessSlots(pre, forkSlot-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.CurrentVersion() != (Version{}) {
+		t.Fatal("state upgraded before fork epoch")
+	}
+	post, err = reg.ProcessSlots(post, forkSlot+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.CurrentVersion() != next.Version || reg.ForkOf(post) != next {
+		t.Fatal("state not upgraded at fork epoch")
+	}
+	if post.GetDomain(DOMAIN_RANDAO, 0) != reg.ComputeDomain(DOMAIN_RANDAO, 0) ||
+		post.GetDomain(DOMAIN_RANDAO, 1) != reg.ComputeDomain(DOMAIN_RANDAO, 1) {
+		t.Error("state domains do not match fork versions")
+	}
+
+	// a block after the fork is signed with the new version
+	upgraded := post.(forks.Phase0State).FullFeaturedState
+	block := produceTestBlock(t, upgraded, keys, forkSlot+2)
+	out, err := reg.StateTransition(forks.Phase0State{FullFeaturedState: copyState(t, genesis)}, forks.Phase0Block{SignedBeaconBlock: block}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.StateRoot() != upgraded.StateRoot() {
+		t.Error("state transition across the fork does not match")
+	}
+	if bls.BLS_ACTIVE {
+		plain := copyState(t, genesis)
+		if err := plain.StateTransition(&phase0.BlockProcessFeature{Block: block, Meta: plain}, true); err == nil {
+			t.Error("expected block of new fork to be invalid without upgrade")
+		}
+	}
+}
+
+// The simulator, sync, fork choice and archive upgrade states at the fork epochs of their registry.
+func TestForkUpgradeCallers(t *testing.T) {
+	next := &forks.Fork{Name: "next", Epoch: 1, Version: Version{1}, Family: forks.Phase0Family{}}
+	reg, err := forks.NewRegistry(forks.Phase0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register(next); err != nil {
+		t.Fatal(err)
+	}
+	cfg := sim.Config{
+		ValidatorCount: uint64(SLOTS_PER_EPOCH) * 4,
+		Seed:           5,
+		GenesisTime:    1564000000,
+		Participation:  1,
+		ForkRegistry:   reg,
+	}
+	s := runSim(t, cfg, 2)
+	if s.State.Fork.CurrentVersion != next.Version {
+		t.Fatal("simulated state was not upgraded at the fork epoch")
+	}
+	last := s.Blocks[len(s.Blocks)-1]
+	expected := s.State.StateRoot()
+	genesisState := func() *phase0.FullFeaturedState {
+		genesis, err := sim.NewSimulator(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return genesis.State
+	}
+
+	t.Run("sync", func(t *testing.T) {
+		replay := chainsync.NewRangeSync(genesisState(), chainsync.NewMemoryBlockSource(s.Blocks...))
+		replay.Forks = reg
+		if err := replay.SyncTo(last.Message.Slot); err != nil {
+			t.Fatal(err)
+		}
+		replay.State.ProcessSlots(s.State.Slot)
+		if replay.State.StateRoot() != expected {
+			t.Error("synced state does not match simulated state")
+		}
+		// without the fork, the blocks after the fork epoch are invalid
+		plain := chainsync.NewRangeSync(genesisState(), chainsync.NewMemoryBlockSource(s.Blocks...))
+		if err := plain.SyncTo(last.Message.Slot); err == nil {
+			t.Error("expected sync without forks to fail")
+		}
+	})
+
+	t.Run("fork choice", func(t *testing.T) {
+		store := forkchoice.NewStore(genesisState())
+		store.Forks = reg
+		for _, b := range s.Blocks {
+			store.OnTick(cfg.GenesisTime + Timestamp(b.Message.Slot)*SECONDS_PER_SLOT)
+			if err := store.OnBlock(b); err != nil {
+				t.Fatalf("block at slot %d: %v", b.Message.Slot, err)
+			}
+		}
+		head, err := store.GetHead()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head != ssz.HashTreeRoot(&last.Message, phase0.BeaconBlockSSZ) {
+			t.Error("unexpected head")
+		}
+		if store.BlockStates[head].Fork.CurrentVersion != next.Version {
+			t.Error("head state was not upgraded")
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		arch, err := archive.NewArchive(genesisState(), SLOTS_PER_EPOCH*4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arch.Forks = reg
+		state := genesisState()
+		for _, b := range s.Blocks {
+			if state, err = reg.Phase0StateTransition(state, b, true); err != nil {
+				t.Fatal(err)
+			}
+			if err := arch.AddBlock(b, state); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// regenerated from the genesis snapshot, across the fork
+		regenerated, err := arch.StateAt(last.Message.Slot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if regenerated.StateRoot() != state.StateRoot() {
+			t.Error("regenerated state does not match")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package benches
+
+import (
+	"crypto/sha256"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/eth1follow"
+	. "github.com/protolambda/zrnt/eth2/phase0"
+	"testing"
+)
+
+// Creates eth1 blocks with the given amount of deposits per block, and time between the blocks.
+// The first validator deposits half of its balance first, and tops up to the full balance later.
+func createGenesisLogBlocks(count uint64, depositsPerBlock uint64, start Timestamp, interval Timestamp) (out []*eth1follow.LogBlock) {
+	deposits := DepositIndex(0)
+	validators := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		b := &eth1follow.LogBlock{
+			Hash:      sha256.Sum256([]byte{0x9e, byte(i >> 8), byte(i)}),
+			Number:    i,
+			Timestamp: start + Timestamp(i)*interval,
+		}
+		for j := uint64(0); j < depositsPerBlock; j++ {
+			var data DepositData
+			if deposits == 0 || (i == 3 && j == 0) {
+				data = createTestDepositData(0, MAX_EFFECTIVE_BALANCE/2)
+			} else {
+				data = createTestDepositData(validators, MAX_EFFECTIVE_BALANCE)
+			}
+			if !(i == 3 && j == 0) {
+				validators++
+			}
+			b.Logs = append(b.Logs, eth1follow.DepositLog{Index: deposits, Data: data})
+			deposits++
+		}
+		out = append(out, b)
+	}
+	return
+}
+
+func checkGenesisWatcher(t *testing.T, blocks []*eth1follow.LogBlock) {
+	// The slow reference: the genesis state from scratch at every eth1 block
+	follower := eth1follow.NewDepositFollower(eth1follow.NewMemoryLogSource(blocks...))
+	if _, err := follower.Follow(); err != nil {
+		t.Fatal(err)
+	}
+	var expectedBlock *eth1follow.LogBlock
+	var expected *FullFeaturedState
+	for i, b := range blocks {
+		// not enough validators to create a genesis state yet, or too early
+		if follower.Chain.Blocks[i].DepositCount < MIN_GENESIS_ACTIVE_VALIDATOR_COUNT ||
+			ComputeGenesisTime(b.Timestamp) < MIN_GENESIS_TIME {
+			continue
+		}
+		state, err := follower.GenesisState(b.Hash, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IsValidGenesisState(state.BeaconState) {
+			expectedBlock, expected = b, state
+			break
+		}
+	}
+	if expected == nil {
+		t.Fatal("test chain does not trigger genesis")
+	}
+
+	w := eth1follow.NewEth1GenesisWatcher()
+	state, err := w.Watch(eth1follow.NewMemoryLogSource(blocks...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil {
+		t.Fatal("genesis was not triggered")
+	}
+	if w.GenesisBlock != expectedBlock {
+		t.Fatalf("genesis triggered at eth1 block %d, expected block %d", w.GenesisBlock.Number, expectedBlock.Number)
+	}
+	if state.StateRoot() != expected.StateRoot() {
+		t.Fatal("genesis state does not match the genesis state computed from scratch")
+	}
+	if _, err := w.ProcessBlock(blocks[len(blocks)-1]); err != eth1follow.ErrGenesisTriggered {
+		t.Fatalf("expected genesis to be triggered already, got %v", err)
+	}
+}
+
+func TestEth1GenesisWatcher(t *testing.T) {
+	if MIN_GENESIS_ACTIVE_VALIDATOR_COUNT > 256 {
+		t.Skip("too many genesis validators to create in a test")
+	}
+	perBlock := uint64(4)
+	blockCount := MIN_GENESIS_ACTIVE_VALIDATOR_COUNT/perBlock + 4
+	t.Run("validator count trigger", func(t *testing.T) {
+		// genesis time is reached early
+		checkGenesisWatcher(t, createGenesisLogBlocks(blockCount, perBlock, MIN_GENESIS_TIME-SECONDS_PER_DAY, 60*60))
+	})
+	t.Run("genesis time trigger", func(t *testing.T) {
+		// validator count is reached early
+		start := MIN_GENESIS_TIME - 2*SECONDS_PER_DAY - Timestamp(blockCount)*60*60
+		checkGenesisWatcher(t, createGenesisLogBlocks(blockCount+4, perBlock, start, 60*60))
+	})
+}
@@ -0,0 +1,230 @@
+package benches
+
+import (
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	. "github.com/protolambda/zrnt/eth2/beacon/exits"
+	. "github.com/protolambda/zrnt/eth2/beacon/header"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/attslash"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/propslash"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/gossip"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"testing"
+	"time"
+)
+
+func TestGossipValidation(t *testing.T) {
+	state := CreateTestState(200, MAX_EFFECTIVE_BALANCE)
+	state.ProcessSlots(SLOTS_PER_EPOCH + 3)
+	gv := gossip.NewGossipValidator(state)
+	slotTime := func(slot Slot) time.Time {
+		return time.Unix(int64(state.GenesisTime+Timestamp(slot)*SECONDS_PER_SLOT), 0)
+	}
+	gv.Now = func() time.Time {
+		return slotTime(state.Slot)
+	}
+
+	expect := func(name string, expected gossip.Result, res gossip.Result, err error) {
+		t.Helper()
+		if res != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", name, expected, res, err)
+		}
+		if (res == gossip.Accept) != (err == nil) {
+			t.Errorf("%s: unexpected error for %s: %v", name, res, err)
+		}
+	}
+
+	res, err := gv.ValidateBlock(&phase0.SignedBeaconBlock{Message: phase0.BeaconBlock{Slot: state.Slot + 1}})
+	expect("future block", gossip.Ignore, res, err)
+	res, err = gv.ValidateBlock(&phase0.SignedBeaconBlock{Message: phase0.BeaconBlock{Slot: 0}})
+	expect("finalized block", gossip.Ignore, res, err)
+
+	committee := state.GetBeaconCommittee(state.Slot-1, 0)
+	bits := make(CommitteeBits, (len(committee)/8)+1)
+	bits.SetBit(uint64(len(committee)), true)
+	bits.SetBit(0, true)
+	att := &Attestation{
+		AggregationBits: bits,
+		Data: AttestationData{
+			Slot:   state.Slot - 1,
+			Index:  0,
+			Target: Checkpoint{Epoch: (state.Slot - 1).ToEpoch()},
+		},
+	}
+	subnet := gossip.ComputeSubnetForAttestation(state.GetCommitteeCountAtSlot(att.Data.Slot), att.Data.Slot, 0)
+	res, err = gv.ValidateAttestation(subnet+1, att)
+	expect("wrong subnet", gossip.Reject, res, err)
+	bits.SetBit(1, true)
+	res, err = gv.ValidateAttestation(subnet, att)
+	expect("aggregated attestation", gossip.Reject, res, err)
+
+	// attestation of the next slot, only accepted within the clock disparity
+	next := *att
+	next.Data.Slot = state.Slot + 1
+	gv.Now = func() time.Time {
+		return slotTime(state.Slot + 1).Add(-gossip.MAXIMUM_GOSSIP_CLOCK_DISPARITY * 2)
+	}
+	nextSubnet := gossip.ComputeSubnetForAttestation(state.GetCommitteeCountAtSlot(next.Data.Slot), next.Data.Slot, 0)
+	res, err = gv.ValidateAttestation(nextSubnet, &next)
+	expect("future attestation", gossip.Ignore, res, err)
+	gv.Now = func() time.Time {
+		return slotTime(state.Slot + 1).Add(-gossip.MAXIMUM_GOSSIP_CLOCK_DISPARITY / 2)
+	}
+	res, err = gv.ValidateAttestation(nextSubnet, &next)
+	expect("attestation within clock disparity", gossip.Reject, res, err)
+
+	gv.Now = func() time.Time {
+		return slotTime(state.Slot + gossip.ATTESTATION_PROPAGATION_SLOT_RANGE + 1)
+	}
+	res, err = gv.ValidateAttestation(subnet, att)
+	expect("old attestation", gossip.Ignore, res, err)
+
+	gv.Now = func() time.Time {
+		return slotTime(0).Add(-time.Second)
+	}
+	res, err = gv.ValidateAttestation(subnet, att)
+	expect("attestation before genesis", gossip.Ignore, res, err)
+
+	res, err = gv.ValidateVoluntaryExit(&SignedVoluntaryExit{Message: VoluntaryExit{ValidatorIndex: 1}})
+	expect("exit too soon", gossip.Reject, res, err)
+}
+
+func TestGossipSlashings(t *testing.T) {
+	state, keys := createKeyedTestState(t, 64)
+	state.ProcessSlots(3)
+	gv := gossip.NewGossipValidator(state)
+	gv.Now = func() time.Time {
+		return time.Unix(int64(state.GenesisTime+Timestamp(state.Slot)*SECONDS_PER_SLOT), 0)
+	}
+
+	expect := func(name string, expected gossip.Result, res gossip.Result, err error) {
+		t.Helper()
+		if res != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", name, expected, res, err)
+		}
+	}
+
+	signHeader := func(index ValidatorIndex, h BeaconBlockHeader) SignedBeaconBlockHeader {
+		return SignedBeaconBlockHeader{Message: h, Signature: bls.BlsSign(keys[index],
+			ssz.HashTreeRoot(&h, BeaconBlockHeaderSSZ), state.GetDomain(DOMAIN_BEACON_PROPOSER, h.Slot.ToEpoch()))}
+	}
+	ps := &ProposerSlashing{
+		ProposerIndex: 3,
+		SignedHeader1: signHeader(3, BeaconBlockHeader{Slot: 2, BodyRoot: Root{1}}),
+		SignedHeader2: signHeader(3, BeaconBlockHeader{Slot: 2, BodyRoot: Root{2}}),
+	}
+	invalid := *ps
+	invalid.SignedHeader2 = invalid.SignedHeader1
+	res, err := gv.ValidateProposerSlashing(&invalid)
+	expect("proposer slashing of same headers", gossip.Reject, res, err)
+	res, err = gv.ValidateProposerSlashing(ps)
+	expect("proposer slashing", gossip.Accept, res, err)
+	res, err = gv.ValidateProposerSlashing(ps)
+	expect("repeated proposer slashing", gossip.Ignore, res, err)
+
+	signAttestation := func(indices []ValidatorIndex, data AttestationData) IndexedAttestation {
+		sigs := make([]BLSSignature, 0, len(indices))
+		for _, i := range indices {
+			sigs = append(sigs, bls.BlsSign(keys[i], ssz.HashTreeRoot(&data, AttestationDataSSZ),
+				state.GetDomain(DOMAIN_BEACON_ATTESTER, data.Target.Epoch)))
+		}
+		sig, err := bls.BlsAggregateSignatures(sigs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return IndexedAttestation{AttestingIndices: indices, Data: data, Signature: sig}
+	}
+	as := &AttesterSlashing{
+		Attestation1: signAttestation([]ValidatorIndex{5, 7}, AttestationData{Slot: 1, BeaconBlockRoot: Root{1}}),
+		Attestation2: signAttestation([]ValidatorIndex{7, 9}, AttestationData{Slot: 1, BeaconBlockRoot: Root{2}}),
+	}
+	res, err = gv.ValidateAttesterSlashing(as)
+	expect("attester slashing", gossip.Accept, res, err)
+	res, err = gv.ValidateAttesterSlashing(as)
+	expect("repeated attester slashing", gossip.Ignore, res, err)
+
+	// validator 7 was seen already, but unsorted indices are invalid regardless
+	unsorted := *as
+	unsorted.Attestation2.AttestingIndices = CommitteeIndices{9, 7}
+	res, err = gv.ValidateAttesterSlashing(&unsorted)
+	expect("attester slashing with unsorted indices", gossip.Reject, res, err)
+	duplicate := *as
+	duplicate.Attestation1.AttestingIndices = CommitteeIndices{7, 7}
+	res, err = gv.ValidateAttesterSlashing(&duplicate)
+	expect("attester slashing with duplicate indices", gossip.Reject, res, err)
+}
+
+func TestSubnetPlanning(t *testing.T) {
+	// committees are numbered from the start of the epoch, and wrap around the subnet count
+	for _, c := range []struct {
+		committeesPerSlot uint64
+		slot              Slot
+		index             CommitteeIndex
+		subnet            uint64
+	}{
+		{1, 0, 0, 0},
+		{4, SLOTS_PER_EPOCH*5 + 3, 2, 14},
+		{4, SLOTS_PER_EPOCH + 3, 0, 12},
+		{64, SLOTS_PER_EPOCH*2 + 3, 5, 5},
+		{64, SLOTS_PER_EPOCH - 1, 63, 63},
+	} {
+		if subnet := gossip.ComputeSubnetForAttestation(c.committeesPerSlot, c.slot, c.index); subnet != c.subnet {
+			t.Errorf("committee %d at slot %d with %d committees per slot: expected subnet %d, got %d",
+				c.index, c.slot, c.committeesPerSlot, c.subnet, subnet)
+		}
+	}
+
+	state := CreateTestState(200, MAX_EFFECTIVE_BALANCE)
+	state.ProcessSlots(SLOTS_PER_EPOCH + 3)
+	planner := &gossip.SubnetPlanner{Meta: state}
+
+	// all 200 validators: 32 committees per epoch, on the first 32 subnets
+	all := make([]ValidatorIndex, 200)
+	for i := range all {
+		all[i] = ValidatorIndex(i)
+	}
+	plans, err := planner.PlanSubnets(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 || plans[0].Epoch != 1 || plans[1].Epoch != 2 {
+		t.Fatalf("unexpected plans: %v", plans)
+	}
+	for _, plan := range plans {
+		// every active validator attests exactly once per epoch
+		if len(plan.Duties) != len(all) {
+			t.Errorf("epoch %d: expected %d duties, got %d", plan.Epoch, len(all), len(plan.Duties))
+		}
+		subnets := plan.Subnets()
+		if len(subnets) != 32 {
+			t.Errorf("epoch %d: expected 32 subnets, got %v", plan.Epoch, subnets)
+		}
+		for i, subnet := range subnets {
+			if subnet != uint64(i) {
+				t.Errorf("epoch %d: expected subnets 0 to 31, got %v", plan.Epoch, subnets)
+				break
+			}
+		}
+		for _, d := range plan.Duties {
+			if d.Slot.ToEpoch() != plan.Epoch {
+				t.Errorf("duty %v outside of epoch %d", d, plan.Epoch)
+			}
+			if _, ok := plan.SlotSubnets(d.Slot)[d.Subnet]; !ok {
+				t.Errorf("duty %v is missing in slot subnets", d)
+			}
+		}
+	}
+
+	// the committees of other epochs are not known
+	for _, epoch := range []Epoch{0, 3, 100} {
+		if _, err := planner.PlanEpoch(epoch, all); (err == nil) != (epoch == 0) {
+			t.Errorf("epoch %d: unexpected planning result: %v", epoch, err)
+		}
+	}
+	state.ProcessSlots(SLOTS_PER_EPOCH * 3)
+	if _, err := planner.PlanEpoch(1, all); err == nil {
+		t.Error("expected an error for an epoch before the previous epoch")
+	}
+}
@@ -0,0 +1,74 @@
+package benches
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/eth1"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/meta"
+	"testing"
+)
+
+type recordingObserver struct {
+	meta.NoopObserver
+	slots     []Slot
+	epochs    []Epoch
+	exits     []ValidatorIndex
+	slashings []ValidatorIndex
+	eth1Data  []Root
+}
+
+func (o *recordingObserver) SlotProcessed(slot Slot) {
+	o.slots = append(o.slots, slot)
+}
+
+func (o *recordingObserver) EpochStarted(epoch Epoch) {
+	o.epochs = append(o.epochs, epoch)
+}
+
+func (o *recordingObserver) ExitInitiated(index ValidatorIndex, exitEpoch Epoch) {
+	o.exits = append(o.exits, index)
+}
+
+func (o *recordingObserver) ValidatorSlashed(index ValidatorIndex, whistleblower ValidatorIndex) {
+	o.slashings = append(o.slashings, index)
+}
+
+func (o *recordingObserver) Eth1DataUpdated(depositRoot Root, depositCount DepositIndex, blockHash Root) {
+	o.eth1Data = append(o.eth1Data, blockHash)
+}
+
+func TestObserverEvents(t *testing.T) {
+	state := CreateTestState(100, MAX_EFFECTIVE_BALANCE)
+	obs := new(recordingObserver)
+	state.Observer = obs
+
+	state.ProcessSlots(SLOTS_PER_EPOCH * 2)
+	if Slot(len(obs.slots)) != SLOTS_PER_EPOCH*2 || obs.slots[0] != 0 || obs.slots[len(obs.slots)-1] != SLOTS_PER_EPOCH*2-1 {
+		t.Errorf("unexpected slot events: %v", obs.slots)
+	}
+	if len(obs.epochs) != 2 || obs.epochs[0] != 1 || obs.epochs[1] != 2 {
+		t.Errorf("unexpected epoch events: %v", obs.epochs)
+	}
+
+	state.SlashValidator(5, nil)
+	if len(obs.slashings) != 1 || obs.slashings[0] != 5 {
+		t.Errorf("unexpected slashing events: %v", obs.slashings)
+	}
+	if len(obs.exits) != 1 || obs.exits[0] != 5 {
+		t.Errorf("unexpected exit events: %v", obs.exits)
+	}
+	// exit was already initiated, no new event
+	state.InitiateValidatorExit(state.CurrentEpoch(), 5)
+	if len(obs.exits) != 1 {
+		t.Errorf("unexpected exit events: %v", obs.exits)
+	}
+
+	data := eth1.Eth1Data{BlockHash: Root{1}}
+	for i := Slot(0); i <= SLOTS_PER_ETH1_VOTING_PERIOD/2; i++ {
+		if err := state.ProcessEth1Vote(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(obs.eth1Data) != 1 || obs.eth1Data[0] != data.BlockHash {
+		t.Errorf("unexpected eth1 data events: %v", obs.eth1Data)
+	}
+}
@@ -0,0 +1,101 @@
+package benches
+
+import (
+	"bytes"
+	"errors"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	. "github.com/protolambda/zrnt/eth2/reqresp"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestReqRespCodec(t *testing.T) {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+
+	// responder: serves a range of empty blocks, and fails after that
+	go func() {
+		var req BeaconBlocksByRange
+		if err := DecodeRequest(reqR, &req, BeaconBlocksByRangeSSZ); err != nil {
+			respW.CloseWithError(err)
+			return
+		}
+		for i := uint64(0); i < req.Count; i++ {
+			block := &phase0.SignedBeaconBlock{Message: phase0.BeaconBlock{Slot: req.StartSlot + Slot(i*req.Step)}}
+			if err := EncodeResponseChunk(respW, block, phase0.SignedBeaconBlockSSZ); err != nil {
+				respW.CloseWithError(err)
+				return
+			}
+		}
+		_ = EncodeErrorChunk(respW, ServerErrorCode, "no more blocks")
+		_ = respW.Close()
+	}()
+
+	req := &BeaconBlocksByRange{HeadBlockRoot: Root{1}, StartSlot: 10, Count: 3, Step: 2}
+	go func() {
+		_ = EncodeRequest(reqW, req, BeaconBlocksByRangeSSZ)
+	}()
+
+	for i := uint64(0); i < req.Count; i++ {
+		var block phase0.SignedBeaconBlock
+		if err := DecodeResponseChunk(respR, &block, phase0.SignedBeaconBlockSSZ); err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		if expected := req.StartSlot + Slot(i*req.Step); block.Message.Slot != expected {
+			t.Errorf("chunk %d: expected slot %d, got %d", i, expected, block.Message.Slot)
+		}
+	}
+	var block phase0.SignedBeaconBlock
+	err := DecodeResponseChunk(respR, &block, phase0.SignedBeaconBlockSSZ)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.Code != ServerErrorCode || respErr.Message != "no more blocks" {
+		t.Errorf("expected error response, got: %v", err)
+	}
+	if err := DecodeResponseChunk(respR, &block, phase0.SignedBeaconBlockSSZ); err != io.EOF {
+		t.Errorf("expected end of response, got: %v", err)
+	}
+}
+
+func TestReqRespInvalidPayload(t *testing.T) {
+	var buf bytes.Buffer
+	status := &Status{HeadSlot: 123}
+	if err := EncodeRequest(&buf, status, StatusSSZ); err != nil {
+		t.Fatal(err)
+	}
+	var ping Ping
+	// the length prefix of a status does not match the size of a ping
+	if err := DecodeRequest(bytes.NewReader(buf.Bytes()), &ping, PingSSZ); err == nil {
+		t.Error("expected size error")
+	}
+	var out Status
+	if err := DecodeRequest(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), &out, StatusSSZ); err == nil {
+		t.Error("expected error for truncated payload")
+	}
+	if err := DecodeRequest(bytes.NewReader(buf.Bytes()), &out, StatusSSZ); err != nil || out != *status {
+		t.Errorf("expected status round-trip, got %v (%v)", out, err)
+	}
+}
+
+func TestReqRespErrorTruncation(t *testing.T) {
+	// 3-byte characters, the limit of 256 bytes falls within a character
+	msg := strings.Repeat("€", 100)
+	var buf bytes.Buffer
+	if err := EncodeErrorChunk(&buf, InvalidRequestCode, msg); err != nil {
+		t.Fatal(err)
+	}
+	var ping Ping
+	err := DecodeResponseChunk(&buf, &ping, PingSSZ)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		t.Fatalf("expected error response, got: %v", err)
+	}
+	if !utf8.ValidString(respErr.Message) || !strings.HasPrefix(msg, respErr.Message) {
+		t.Errorf("expected message to be truncated at a character boundary: %q", respErr.Message)
+	}
+	if len(respErr.Message) != 255 {
+		t.Errorf("expected 85 characters, got %d bytes", len(respErr.Message))
+	}
+}
@@ -0,0 +1,134 @@
+package benches
+
+import (
+	"github.com/protolambda/zrnt/eth2/chainsync"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/sim"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func runSim(t *testing.T, cfg sim.Config, epochs Epoch) *sim.Simulator {
+	s, err := sim.NewSimulator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunEpochs(epochs); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSimFinality(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := sim.Config{
+		ValidatorCount:   uint64(SLOTS_PER_EPOCH) * 4,
+		Seed:             42,
+		GenesisTime:      1564000000,
+		Participation:    1,
+		Offline:          []ValidatorIndex{3},
+		ProposerMissRate: 0.1,
+		DepositRate:      0.2,
+		SlashingRate:     0.1,
+		ForkRate:         0.2,
+		DumpDir:          dir,
+	}
+	s := runSim(t, cfg, 5)
+	if fin := s.State.FinalizedCheckpoint.Epoch; fin < 2 {
+		t.Errorf("expected finality with full participation, finalized epoch is %d", fin)
+	}
+	if len(s.Forks) == 0 {
+		t.Error("expected forks")
+	}
+	if uint64(len(s.State.Validators)) <= cfg.ValidatorCount {
+		t.Error("expected deposits to add validators")
+	}
+	slashed := 0
+	for _, v := range s.State.Validators {
+		if v.Slashed {
+			slashed++
+		}
+	}
+	if slashed == 0 {
+		t.Error("expected slashings")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "blocks_0.ssz")); err != nil {
+		t.Errorf("expected block dump: %v", err)
+	}
+
+	// the simulated chain is deterministic, and valid when replayed
+	genesis, err := sim.NewSimulator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replay from the dumped blocks
+	dumped, err := chainsync.LoadDumpBlockSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := chainsync.NewRangeSync(genesis.State, dumped)
+	if err := replay.SyncTo(s.Blocks[len(s.Blocks)-1].Message.Slot); err != nil {
+		t.Fatal(err)
+	}
+	replay.State.ProcessSlots(s.State.Slot)
+	if replay.State.StateRoot() != s.State.StateRoot() {
+		t.Error("replayed state does not match simulated state")
+	}
+	if again := runSim(t, cfg, 5); again.State.StateRoot() != s.State.StateRoot() {
+		t.Error("simulation with the same seed is not deterministic")
+	}
+}
+
+func TestSimLowParticipation(t *testing.T) {
+	s := runSim(t, sim.Config{
+		ValidatorCount: uint64(SLOTS_PER_EPOCH) * 4,
+		Seed:           7,
+		GenesisTime:    1564000000,
+		Participation:  0.5,
+	}, 3)
+	if fin := s.State.FinalizedCheckpoint.Epoch; fin != 0 {
+		t.Errorf("expected no finality with half participation, finalized epoch is %d", fin)
+	}
+}
+
+func TestSimExits(t *testing.T) {
+	s, err := sim.NewSimulator(sim.Config{
+		ValidatorCount: uint64(SLOTS_PER_EPOCH) * 4,
+		Seed:           3,
+		GenesisTime:    1564000000,
+		Participation:  1,
+		ExitRate:       0.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Validators can only exit after PERSISTENT_COMMITTEE_PERIOD epochs of activity.
+	// Start the chain that much later, instead of simulating all those epochs.
+	s.State.Slot = (GENESIS_EPOCH + PERSISTENT_COMMITTEE_PERIOD).GetStartSlot()
+	s.State.LoadPrecomputedData()
+	if err := s.RunEpochs(2); err != nil {
+		t.Fatal(err)
+	}
+	exits := 0
+	for _, b := range s.Blocks {
+		exits += len(b.Message.Body.VoluntaryExits)
+	}
+	if exits == 0 {
+		t.Fatal("expected voluntary exits")
+	}
+	exited := 0
+	for _, v := range s.State.Validators {
+		if v.ExitEpoch != FAR_FUTURE_EPOCH {
+			exited++
+		}
+	}
+	if exited != exits {
+		t.Errorf("expected %d validators to be exiting, got %d", exits, exited)
+	}
+}
@@ -0,0 +1,85 @@
+package benches
+
+import (
+	"bytes"
+	. "github.com/protolambda/zrnt/eth2/core"
+	. "github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/sim"
+	"github.com/protolambda/zrnt/eth2/statediff"
+	"github.com/protolambda/zssz"
+	"testing"
+)
+
+func encodeDecodeDiff(t *testing.T, d *statediff.StateDiff) (*statediff.StateDiff, int) {
+	var buf bytes.Buffer
+	if _, err := zssz.Encode(&buf, d, statediff.StateDiffSSZ); err != nil {
+		t.Fatal(err)
+	}
+	size := buf.Len()
+	out := new(statediff.StateDiff)
+	if err := zssz.Decode(&buf, uint64(size), out, statediff.StateDiffSSZ); err != nil {
+		t.Fatal(err)
+	}
+	return out, size
+}
+
+func TestStateDiff(t *testing.T) {
+	s, err := sim.NewSimulator(sim.Config{
+		ValidatorCount: uint64(SLOTS_PER_EPOCH) * 4,
+		Seed:           7,
+		GenesisTime:    1564000000,
+		Participation:  1,
+		DepositRate:    0.3,
+		SlashingRate:   0.1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one full state at the start of the epoch, and per-slot diffs
+	snapshot := copyState(t, s.State)
+	stateSize := zssz.SizeOf(snapshot.BeaconState, BeaconStateSSZ)
+	var diffs []*statediff.StateDiff
+	prev := snapshot
+	for i := Slot(0); i < SLOTS_PER_EPOCH*2; i++ {
+		if err := s.NextSlot(); err != nil {
+			t.Fatal(err)
+		}
+		next := copyState(t, s.State)
+		d, err := statediff.Diff(prev.BeaconState, next.BeaconState)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, size := encodeDecodeDiff(t, d)
+		if size >= int(stateSize) {
+			t.Errorf("diff of slot %d is not compact: %d bytes, state is %d bytes", next.Slot, size, stateSize)
+		}
+		diffs = append(diffs, decoded)
+		prev = next
+	}
+
+	// reconstruct the latest state from the snapshot
+	state := copyState(t, snapshot)
+	for _, d := range diffs {
+		if err := d.Apply(state.BeaconState, true); err != nil {
+			t.Fatalf("failed to apply diff of slot %d: %v", d.Slot, err)
+		}
+	}
+	if state.StateRoot() != s.State.StateRoot() {
+		t.Fatal("reconstructed state does not match")
+	}
+
+	// a single diff can span multiple epochs
+	d, err := statediff.Diff(snapshot.BeaconState, s.State.BeaconState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state = copyState(t, snapshot)
+	if err := d.Apply(state.BeaconState, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// the diff does not apply to other states
+	if err := diffs[3].Apply(copyState(t, snapshot).BeaconState, true); err == nil {
+		t.Fatal("expected diff to fail on a different pre-state")
+	}
+}
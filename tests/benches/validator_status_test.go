@@ -0,0 +1,146 @@
+package benches
+
+import (
+	. "github.com/protolambda/zrnt/eth2/beacon/validator"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"testing"
+)
+
+func TestValidatorStatus(t *testing.T) {
+	far := FAR_FUTURE_EPOCH
+	cases := []struct {
+		v        Validator
+		epoch    Epoch
+		expected Status
+	}{
+		{Validator{ActivationEligibilityEpoch: far, ActivationEpoch: far, ExitEpoch: far, WithdrawableEpoch: far}, 3, PendingInitialized},
+		{Validator{ActivationEligibilityEpoch: 2, ActivationEpoch: far, ExitEpoch: far, WithdrawableEpoch: far}, 3, PendingQueued},
+		{Validator{ActivationEligibilityEpoch: 2, ActivationEpoch: 5, ExitEpoch: far, WithdrawableEpoch: far}, 3, PendingQueued},
+		{Validator{ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: far, WithdrawableEpoch: far}, 3, ActiveOngoing},
+		{Validator{ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: 10, WithdrawableEpoch: 20}, 3, ActiveExiting},
+		{Validator{ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: 10, WithdrawableEpoch: 20, Slashed: true}, 3, ActiveSlashed},
+		{Validator{ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: 10, WithdrawableEpoch: 20}, 10, ExitedUnslashed},
+		{Validator{ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: 10, WithdrawableEpoch: 20, Slashed: true}, 19, ExitedSlashed},
+		{Validator{ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: 10, WithdrawableEpoch: 20, Slashed: true}, 20, WithdrawalPossible},
+	}
+	for i, c := range cases {
+		if s := c.v.Status(c.epoch); s != c.expected {
+			t.Errorf("case %d: expected status %s, got %s", i, c.expected, s)
+		}
+	}
+}
+
+func TestEstimateActivationEpoch(t *testing.T) {
+	state := CreateTestState(uint64(SLOTS_PER_EPOCH)*4, MAX_EFFECTIVE_BALANCE)
+	churnLimit := state.GetChurnLimit(state.CurrentEpoch())
+	// more pending validators than can be activated in a single epoch
+	pendingCount := churnLimit*2 + 1
+	first := ValidatorIndex(len(state.Validators))
+	for i := uint64(0); i < pendingCount; i++ {
+		state.AddNewValidator(BLSPubkey{0xcc, byte(i)}, Root{0xdd, byte(i)}, MAX_EFFECTIVE_BALANCE)
+	}
+	// not enough balance to join the queue
+	insufficient := ValidatorIndex(len(state.Validators))
+	state.AddNewValidator(BLSPubkey{0xcc, 0xff}, Root{0xdd, 0xff}, MAX_EFFECTIVE_BALANCE/2)
+
+	if e := state.EstimateActivationEpoch(insufficient, state.CurrentEpoch(), state.FinalizedCheckpoint.Epoch); e != FAR_FUTURE_EPOCH {
+		t.Errorf("expected no activation estimate for validator with insufficient balance, got %d", e)
+	}
+	estimates := make([]Epoch, pendingCount)
+	for i := range estimates {
+		index := first + ValidatorIndex(i)
+		if pos, ok := state.ActivationQueuePosition(index, state.CurrentEpoch()); !ok || pos != uint64(i) {
+			t.Fatalf("validator %d: unexpected queue position %d (%v)", index, pos, ok)
+		}
+		if s := state.Validators[index].Status(state.CurrentEpoch()); s != PendingInitialized {
+			t.Fatalf("validator %d: unexpected status %s", index, s)
+		}
+		estimates[i] = state.EstimateActivationEpoch(index, state.CurrentEpoch(), state.FinalizedCheckpoint.Epoch)
+	}
+
+	last := estimates[len(estimates)-1]
+	for state.CurrentEpoch() <= last {
+		// simulate finality keeping up, without attestations
+		if epoch := state.CurrentEpoch(); epoch > GENESIS_EPOCH {
+			state.FinalizedCheckpoint.Epoch = epoch - 1
+		}
+		state.ProcessSlots((state.CurrentEpoch() + 1).GetStartSlot())
+	}
+	for i, e := range estimates {
+		v := state.Validators[first+ValidatorIndex(i)]
+		if v.ActivationEpoch != e {
+			t.Errorf("validator %d: estimated activation at epoch %d, but activated at %d", first+ValidatorIndex(i), e, v.ActivationEpoch)
+		}
+		if s := v.Status(state.CurrentEpoch()); s != ActiveOngoing {
+			t.Errorf("validator %d: unexpected status %s", first+ValidatorIndex(i), s)
+		}
+	}
+	if estimates[0] == last {
+		t.Error("expected the churn limit to spread activations over multiple epochs")
+	}
+}
+
+func TestProjectQueues(t *testing.T) {
+	state := CreateTestState(uint64(SLOTS_PER_EPOCH)*4, MAX_EFFECTIVE_BALANCE)
+	churnLimit := state.GetChurnLimit(state.CurrentEpoch())
+	// more pending validators than can be activated in a single epoch
+	pendingCount := churnLimit*2 + 1
+	first := ValidatorIndex(len(state.Validators))
+	for i := uint64(0); i < pendingCount; i++ {
+		state.AddNewValidator(BLSPubkey{0xcc, byte(i)}, Root{0xdd, byte(i)}, MAX_EFFECTIVE_BALANCE)
+	}
+	// validators to be ejected in the next epoch transition
+	ejected := []ValidatorIndex{0, 1}
+	for _, index := range ejected {
+		state.Validators[index].EffectiveBalance = EJECTION_BALANCE
+		state.Balances[index] = EJECTION_BALANCE
+	}
+	// more voluntary exits than can exit in a single epoch
+	var exits []ValidatorIndex
+	for i := uint64(0); i < churnLimit+1; i++ {
+		exits = append(exits, ValidatorIndex(10+i))
+	}
+
+	currentEpoch := state.CurrentEpoch()
+	proj := state.ProjectQueues(currentEpoch, state.FinalizedCheckpoint.Epoch, exits)
+	for i := uint64(0); i < pendingCount; i++ {
+		if state.Validators[first+ValidatorIndex(i)].ActivationEligibilityEpoch != FAR_FUTURE_EPOCH {
+			t.Fatal("projection changed the state")
+		}
+	}
+	if len(proj.Activations) != int(pendingCount) {
+		t.Errorf("expected %d projected activations, got %d", pendingCount, len(proj.Activations))
+	}
+	if expected := len(ejected) + len(exits); len(proj.Exits) != expected {
+		t.Errorf("expected %d projected exits, got %d", expected, len(proj.Exits))
+	}
+
+	for _, index := range exits {
+		state.InitiateValidatorExit(currentEpoch, index)
+	}
+	var last Epoch
+	for _, e := range proj.Activations {
+		if e > last {
+			last = e
+		}
+	}
+	for state.CurrentEpoch() <= last {
+		// simulate finality keeping up, without attestations
+		if epoch := state.CurrentEpoch(); epoch > GENESIS_EPOCH {
+			state.FinalizedCheckpoint.Epoch = epoch - 1
+		}
+		state.ProcessSlots((state.CurrentEpoch() + 1).GetStartSlot())
+	}
+	for index, e := range proj.Activations {
+		if a := state.Validators[index].ActivationEpoch; a != e {
+			t.Errorf("validator %d: projected activation at epoch %d, but activated at %d", index, e, a)
+		}
+	}
+	for index, e := range proj.Exits {
+		v := state.Validators[index]
+		if v.ExitEpoch != e.ExitEpoch || v.WithdrawableEpoch != e.WithdrawableEpoch {
+			t.Errorf("validator %d: projected exit at epoch %d (withdrawable %d), but exits at %d (withdrawable %d)",
+				index, e.ExitEpoch, e.WithdrawableEpoch, v.ExitEpoch, v.WithdrawableEpoch)
+		}
+	}
+}
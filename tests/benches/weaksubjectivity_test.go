@@ -0,0 +1,86 @@
+package benches
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/weaksubjectivity"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	. "github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"testing"
+)
+
+func TestWeakSubjectivityPeriod(t *testing.T) {
+	cases := []struct {
+		count    uint64
+		expected Epoch
+	}{
+		{0, 256},
+		{1024, 268},
+		{32768, 665},
+		{262144, 3532},
+		{1048576, 3532},
+	}
+	for _, c := range cases {
+		if p := weaksubjectivity.ComputeWeakSubjectivityPeriod(c.count); p != c.expected {
+			t.Errorf("%d validators: expected weak subjectivity period %d, got %d", c.count, c.expected, p)
+		}
+	}
+
+	state := CreateTestState(uint64(SLOTS_PER_EPOCH)*4, MAX_EFFECTIVE_BALANCE)
+	period := state.WeakSubjectivityPeriod()
+	state.Slot = (period + 10).GetStartSlot()
+	if !state.IsWithinWeakSubjectivityPeriod(Checkpoint{Epoch: 10}) {
+		t.Error("expected checkpoint at the end of the period to be within the period")
+	}
+	if state.IsWithinWeakSubjectivityPeriod(Checkpoint{Epoch: 9}) {
+		t.Error("expected checkpoint before the period to be outside of the period")
+	}
+	if !state.IsWithinWeakSubjectivityPeriod(Checkpoint{Epoch: period + 11}) {
+		t.Error("expected future checkpoint to be within the period")
+	}
+}
+
+func TestCheckpointState(t *testing.T) {
+	chain, keys := createKeyedTestState(t, uint64(SLOTS_PER_EPOCH)*4)
+	produceTestBlock(t, chain, keys, 1)
+	signed := produceTestBlock(t, chain, keys, SLOTS_PER_EPOCH+2)
+	block := &signed.Message
+	cp := Checkpoint{Epoch: 2, Root: ssz.HashTreeRoot(block, BeaconBlockSSZ)}
+
+	// the trusted post-state of the checkpoint block
+	trusted := func() *BeaconState {
+		return copyState(t, chain).BeaconState
+	}
+	expected := copyState(t, chain)
+	expected.ProcessSlots(cp.Epoch.GetStartSlot())
+
+	booted, err := CheckpointState(trusted(), block, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := booted.StateRoot(), expected.StateRoot(); a != b {
+		t.Fatalf("booted state root %x does not match expected state root %x", a, b)
+	}
+	// a state processed up to the checkpoint epoch is accepted as well
+	booted, err = CheckpointState(copyState(t, expected).BeaconState, block, cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store := forkchoice.NewStore(booted); store.FinalizedCheckpoint != cp {
+		t.Errorf("expected store anchored at checkpoint %v, got %v", cp, store.FinalizedCheckpoint)
+	}
+	// the booted state can continue the chain
+	produceTestBlock(t, booted, keys, cp.Epoch.GetStartSlot()+1)
+
+	if _, err := CheckpointState(trusted(), block, Checkpoint{Epoch: 2, Root: Root{1}}); err == nil {
+		t.Error("expected checkpoint root mismatch to fail")
+	}
+	tampered := trusted()
+	tampered.LatestBlockHeader.BodyRoot = Root{1}
+	if _, err := CheckpointState(tampered, block, cp); err == nil {
+		t.Error("expected latest block header mismatch to fail")
+	}
+	if _, err := CheckpointState(trusted(), block, Checkpoint{Epoch: 1, Root: cp.Root}); err == nil {
+		t.Error("expected state past the checkpoint epoch to fail")
+	}
+}
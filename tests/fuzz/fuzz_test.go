@@ -0,0 +1,278 @@
+//go:build go1.18
+// +build go1.18
+
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	. "github.com/protolambda/zrnt/eth2/beacon/deposits"
+	. "github.com/protolambda/zrnt/eth2/beacon/exits"
+	. "github.com/protolambda/zrnt/eth2/beacon/header"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/attslash"
+	. "github.com/protolambda/zrnt/eth2/beacon/slashings/propslash"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/sim"
+	"github.com/protolambda/zssz"
+	"github.com/protolambda/zssz/types"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+var (
+	preOnce sync.Once
+	pre     *sim.Simulator
+	preErr  error
+)
+
+// The fixed pre-state: a simulated chain, at the last slot of an epoch, with pending attestations.
+// Blocks of the simulation are used as seeds.
+func preState(f *testing.F) *sim.Simulator {
+	preOnce.Do(func() {
+		pre, preErr = sim.NewSimulator(sim.Config{
+			ValidatorCount: uint64(SLOTS_PER_EPOCH) * 4,
+			Seed:           1,
+			GenesisTime:    1564000000,
+			Participation:  0.9,
+			DepositRate:    0.2,
+			SlashingRate:   0.2,
+		})
+		if preErr == nil {
+			preErr = pre.RunUntil(SLOTS_PER_EPOCH*2 - 1)
+		}
+	})
+	if preErr != nil {
+		f.Fatal(preErr)
+	}
+	return pre
+}
+
+func copyPre(t *testing.T) *phase0.FullFeaturedState {
+	state, err := pre.State.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func encode(f *testing.F, v interface{}, sszTyp types.SSZ) []byte {
+	var buf bytes.Buffer
+	if _, err := zssz.Encode(&buf, v, sszTyp); err != nil {
+		f.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Adds the SSZ files with the given name in the spec test vectors, if they are present, to the corpus.
+func addSpecSeeds(f *testing.F, handlerPath string, name string) {
+	_, filename, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(filepath.Dir(filename)), "spec", "eth2.0-spec-tests", "tests",
+		PRESET_NAME, "phase0", filepath.FromSlash(handlerPath))
+	paths, _ := filepath.Glob(filepath.Join(dir, "*", "*", name+".ssz"))
+	for _, p := range paths {
+		if data, err := ioutil.ReadFile(p); err == nil {
+			f.Add(data)
+		}
+	}
+}
+
+// Decodes the input, or returns false if it is not valid SSZ for the type.
+func decode(data []byte, dst interface{}, sszTyp types.SSZ) bool {
+	return zssz.Decode(bytes.NewReader(data), uint64(len(data)), dst, sszTyp) == nil
+}
+
+// Runs the processing twice, on separate copies of the pre-state, and checks that the results are the same.
+func checkDeterministic(t *testing.T, process func(state *phase0.FullFeaturedState) error) {
+	a, b := copyPre(t), copyPre(t)
+	errA, errB := process(a), process(b)
+	if fmt.Sprint(errA) != fmt.Sprint(errB) {
+		t.Fatalf("different errors: %v <> %v", errA, errB)
+	}
+	if errA == nil && a.StateRoot() != b.StateRoot() {
+		t.Fatal("different post-state roots")
+	}
+}
+
+func FuzzStateTransition(f *testing.F) {
+	s := preState(f)
+	for _, b := range s.Blocks {
+		f.Add(encode(f, b, phase0.SignedBeaconBlockSSZ))
+	}
+	for i := 0; i < 4; i++ {
+		addSpecSeeds(f, "sanity/blocks", fmt.Sprintf("blocks_%d", i))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		block := new(phase0.SignedBeaconBlock)
+		if !decode(data, block, phase0.SignedBeaconBlockSSZ) {
+			return
+		}
+		// Processing empty slots far into the future is slow, not interesting.
+		if block.Message.Slot > pre.State.Slot+SLOTS_PER_EPOCH*2 {
+			return
+		}
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			return state.StateTransition(&phase0.BlockProcessFeature{Block: block, Meta: state}, false)
+		})
+	})
+}
+
+func FuzzProcessHeader(f *testing.F) {
+	s := preState(f)
+	for _, b := range s.Blocks {
+		f.Add(encode(f, b.Message.Header(), BeaconBlockHeaderSSZ))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h := new(BeaconBlockHeader)
+		if !decode(data, h, BeaconBlockHeaderSSZ) {
+			return
+		}
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			return state.ProcessHeader(h)
+		})
+	})
+}
+
+func FuzzProcessAttestation(f *testing.F) {
+	s := preState(f)
+	for _, b := range s.Blocks {
+		for i := range b.Message.Body.Attestations {
+			f.Add(encode(f, &b.Message.Body.Attestations[i], AttestationSSZ))
+		}
+	}
+	addSpecSeeds(f, "operations/attestation", "attestation")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		att := new(Attestation)
+		if !decode(data, att, AttestationSSZ) {
+			return
+		}
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			return state.ProcessAttestation(att)
+		})
+	})
+}
+
+func FuzzProcessAttesterSlashing(f *testing.F) {
+	s := preState(f)
+	for _, b := range s.Blocks {
+		for i := range b.Message.Body.AttesterSlashings {
+			f.Add(encode(f, &b.Message.Body.AttesterSlashings[i], AttesterSlashingSSZ))
+		}
+	}
+	addSpecSeeds(f, "operations/attester_slashing", "attester_slashing")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sl := new(AttesterSlashing)
+		if !decode(data, sl, AttesterSlashingSSZ) {
+			return
+		}
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			return state.ProcessAttesterSlashing(sl)
+		})
+	})
+}
+
+func FuzzProcessProposerSlashing(f *testing.F) {
+	s := preState(f)
+	for _, b := range s.Blocks {
+		for i := range b.Message.Body.ProposerSlashings {
+			f.Add(encode(f, &b.Message.Body.ProposerSlashings[i], ProposerSlashingSSZ))
+		}
+	}
+	addSpecSeeds(f, "operations/proposer_slashing", "proposer_slashing")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sl := new(ProposerSlashing)
+		if !decode(data, sl, ProposerSlashingSSZ) {
+			return
+		}
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			return state.ProcessProposerSlashing(sl)
+		})
+	})
+}
+
+func FuzzProcessDeposit(f *testing.F) {
+	s := preState(f)
+	for _, b := range s.Blocks {
+		for i := range b.Message.Body.Deposits {
+			f.Add(encode(f, &b.Message.Body.Deposits[i], DepositSSZ))
+		}
+	}
+	addSpecSeeds(f, "operations/deposit", "deposit")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dep := new(Deposit)
+		if !decode(data, dep, DepositSSZ) {
+			return
+		}
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			return state.ProcessDeposit(dep)
+		})
+	})
+}
+
+func FuzzProcessVoluntaryExit(f *testing.F) {
+	s := preState(f)
+	for _, b := range s.Blocks {
+		for i := range b.Message.Body.VoluntaryExits {
+			f.Add(encode(f, &b.Message.Body.VoluntaryExits[i], SignedVoluntaryExitSSZ))
+		}
+	}
+	f.Add(make([]byte, 8+8+96))
+	addSpecSeeds(f, "operations/voluntary_exit", "voluntary_exit")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		exit := new(SignedVoluntaryExit)
+		if !decode(data, exit, SignedVoluntaryExitSSZ) {
+			return
+		}
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			return state.ProcessVoluntaryExit(exit)
+		})
+	})
+}
+
+var epochAttestationsSSZ = zssz.GetSSZ((*EpochPendingAttestations)(nil))
+
+// Keeps the pending attestations with the shape that attestation processing guarantees for the current epoch.
+// Other attestations can never be part of a state, and are expected to fail hard in the epoch transition.
+func validPending(state *phase0.FullFeaturedState, atts EpochPendingAttestations) EpochPendingAttestations {
+	currentEpoch := state.CurrentEpoch()
+	out := atts[:0]
+	for _, a := range atts {
+		data := &a.Data
+		if data.Target.Epoch != currentEpoch || data.Slot.ToEpoch() != currentEpoch ||
+			a.InclusionDelay < MIN_ATTESTATION_INCLUSION_DELAY || data.Slot+a.InclusionDelay > state.Slot ||
+			uint64(data.Index) >= state.GetCommitteeCountAtSlot(data.Slot) ||
+			uint64(a.ProposerIndex) >= uint64(len(state.Validators)) {
+			continue
+		}
+		if a.AggregationBits.BitLen() != uint64(len(state.GetBeaconCommittee(data.Slot, data.Index))) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// Processes the slot across the epoch boundary, with a fuzzed set of current epoch attestations,
+// to run the justification, rewards and registry updates of the epoch transition.
+func FuzzEpochTransition(f *testing.F) {
+	s := preState(f)
+	f.Add(encode(f, &s.State.CurrentEpochAttestations, epochAttestationsSSZ))
+	f.Add(encode(f, &s.State.PreviousEpochAttestations, epochAttestationsSSZ))
+	f.Add(encode(f, &EpochPendingAttestations{}, epochAttestationsSSZ))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var atts EpochPendingAttestations
+		if !decode(data, &atts, epochAttestationsSSZ) {
+			return
+		}
+		atts = validPending(pre.State, atts)
+		checkDeterministic(t, func(state *phase0.FullFeaturedState) error {
+			state.CurrentEpochAttestations = atts
+			state.ProcessSlots(state.Slot + 1)
+			return nil
+		})
+	})
+}
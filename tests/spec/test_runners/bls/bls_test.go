@@ -0,0 +1,129 @@
+package bls
+
+import (
+	"encoding/hex"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/tests/spec/test_util"
+	"gopkg.in/yaml.v2"
+	"strings"
+	"testing"
+)
+
+// Decodes a 0x prefixed hex string into the destination, which must match the length exactly.
+func decodeHex(t *testing.T, src string, dst []byte) {
+	b, err := hex.DecodeString(strings.TrimPrefix(src, "0x"))
+	test_util.Check(t, err)
+	if len(b) != len(dst) {
+		t.Fatalf("expected %d bytes, got %d", len(dst), len(b))
+	}
+	copy(dst, b)
+}
+
+// Loads the data.yaml of a BLS test case into dst.
+func loadData(t *testing.T, readPart test_util.TestPartReader, dst interface{}) {
+	p := readPart("data.yaml")
+	dec := yaml.NewDecoder(p)
+	test_util.Check(t, dec.Decode(dst))
+	test_util.Check(t, p.Close())
+}
+
+// Note: the msg_hash_g2_compressed and msg_hash_g2_uncompressed handlers are not covered, they test the
+// hash-to-curve internals, which are not part of the BLS API used by the state transition.
+func runBLSTest(t *testing.T, handler string, caseRunner test_util.CaseRunner) {
+	test_util.RunHandler(t, "bls/"+handler,
+		func(t *testing.T, readPart test_util.TestPartReader) {
+			if !bls.BLS_ACTIVE {
+				t.Skip("skipping BLS test because BLS is disabled")
+			}
+			caseRunner(t, readPart)
+		}, "general")
+}
+
+func TestPrivToPub(t *testing.T) {
+	runBLSTest(t, "priv_to_pub", func(t *testing.T, readPart test_util.TestPartReader) {
+		var data struct {
+			Input  string `yaml:"input"`
+			Output string `yaml:"output"`
+		}
+		loadData(t, readPart, &data)
+		var priv [32]byte
+		decodeHex(t, data.Input, priv[:])
+		var expected BLSPubkey
+		decodeHex(t, data.Output, expected[:])
+		if got := bls.BlsSecretToPubkey(priv); got != expected {
+			t.Errorf("expected pubkey %x, got %x", expected, got)
+		}
+	})
+}
+
+func TestSignMsg(t *testing.T) {
+	runBLSTest(t, "sign_msg", func(t *testing.T, readPart test_util.TestPartReader) {
+		var data struct {
+			Input struct {
+				Privkey string `yaml:"privkey"`
+				Message string `yaml:"message"`
+				Domain  string `yaml:"domain"`
+			} `yaml:"input"`
+			Output string `yaml:"output"`
+		}
+		loadData(t, readPart, &data)
+		var priv [32]byte
+		decodeHex(t, data.Input.Privkey, priv[:])
+		var msg Root
+		decodeHex(t, data.Input.Message, msg[:])
+		var domain BLSDomain
+		decodeHex(t, data.Input.Domain, domain[:])
+		var expected BLSSignature
+		decodeHex(t, data.Output, expected[:])
+		got := bls.BlsSign(priv, msg, domain)
+		if got != expected {
+			t.Errorf("expected signature %x, got %x", expected, got)
+		}
+		if !bls.BlsVerify(bls.BlsSecretToPubkey(priv), msg, got, domain) {
+			t.Error("signature does not verify")
+		}
+	})
+}
+
+func TestAggregatePubkeys(t *testing.T) {
+	runBLSTest(t, "aggregate_pubkeys", func(t *testing.T, readPart test_util.TestPartReader) {
+		var data struct {
+			Input  []string `yaml:"input"`
+			Output string   `yaml:"output"`
+		}
+		loadData(t, readPart, &data)
+		pubkeys := make([]BLSPubkey, len(data.Input))
+		for i, in := range data.Input {
+			decodeHex(t, in, pubkeys[i][:])
+		}
+		var expected BLSPubkey
+		decodeHex(t, data.Output, expected[:])
+		if got := bls.BlsAggregatePubkeys(pubkeys); got != expected {
+			t.Errorf("expected aggregate pubkey %x, got %x", expected, got)
+		}
+	})
+}
+
+func TestAggregateSigs(t *testing.T) {
+	runBLSTest(t, "aggregate_sigs", func(t *testing.T, readPart test_util.TestPartReader) {
+		var data struct {
+			Input  []string `yaml:"input"`
+			Output string   `yaml:"output"`
+		}
+		loadData(t, readPart, &data)
+		sigs := make([]BLSSignature, len(data.Input))
+		for i, in := range data.Input {
+			decodeHex(t, in, sigs[i][:])
+		}
+		var expected BLSSignature
+		decodeHex(t, data.Output, expected[:])
+		got, err := bls.BlsAggregateSignatures(sigs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expected {
+			t.Errorf("expected aggregate signature %x, got %x", expected, got)
+		}
+	})
+}
@@ -19,10 +19,16 @@ func (c *EpochTest) Run() error {
 }
 
 func NewEpochTest(fn stateFn) test_util.TransitionCaseMaker {
+	return NewParallelEpochTest(fn, 1)
+}
+
+func NewParallelEpochTest(fn stateFn, concurrency int) test_util.TransitionCaseMaker {
 	return func() test_util.TransitionTest {
-		return &EpochTest{fn: func(state *phase0.FullFeaturedState) {
+		c := &EpochTest{fn: func(state *phase0.FullFeaturedState) {
 			fn(state)
 		}}
+		c.Concurrency = concurrency
+		return c
 	}
 }
 
@@ -53,3 +59,17 @@ func TestSlashings(t *testing.T) {
 			state.ProcessEpochSlashings()
 		}))
 }
+
+func TestFinalUpdatesParallel(t *testing.T) {
+	test_util.RunTransitionTest(t, "epoch_processing", "final_updates",
+		NewParallelEpochTest(func(state *phase0.FullFeaturedState) {
+			state.ProcessEpochFinalUpdates()
+		}, 4))
+}
+
+func TestJustificationAndFinalizationParallel(t *testing.T) {
+	test_util.RunTransitionTest(t, "epoch_processing", "justification_and_finalization",
+		NewParallelEpochTest(func(state *phase0.FullFeaturedState) {
+			state.ProcessEpochJustification()
+		}, 4))
+}
@@ -0,0 +1,59 @@
+package finality
+
+import (
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/tests/spec/test_util"
+	"gopkg.in/yaml.v2"
+	"testing"
+)
+
+type FinalityTestCase struct {
+	test_util.BaseTransitionTest
+	Blocks []*phase0.SignedBeaconBlock
+}
+
+type BlocksCountMeta struct {
+	BlocksCount uint64 `yaml:"blocks_count"`
+}
+
+func (c *FinalityTestCase) Load(t *testing.T, readPart test_util.TestPartReader) {
+	c.BaseTransitionTest.Load(t, readPart)
+	p := readPart("meta.yaml")
+	dec := yaml.NewDecoder(p)
+	m := &BlocksCountMeta{}
+	test_util.Check(t, dec.Decode(&m))
+	test_util.Check(t, p.Close())
+	for i := uint64(0); i < m.BlocksCount; i++ {
+		dst := new(phase0.SignedBeaconBlock)
+		if !test_util.LoadSSZ(t, fmt.Sprintf("blocks_%d", i), dst, phase0.SignedBeaconBlockSSZ, readPart) {
+			t.Fatalf("missing block %d", i)
+		}
+		c.Blocks = append(c.Blocks, dst)
+	}
+}
+
+func (c *FinalityTestCase) Run() error {
+	state := c.Prepare()
+	for _, b := range c.Blocks {
+		blockProc := &phase0.BlockProcessFeature{Block: b, Meta: state}
+		if err := state.StateTransition(blockProc, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFinality(t *testing.T) {
+	test_util.RunTransitionTest(t, "finality", "finality",
+		func() test_util.TransitionTest { return new(FinalityTestCase) })
+}
+
+func TestFinalityParallel(t *testing.T) {
+	test_util.RunTransitionTest(t, "finality", "finality",
+		func() test_util.TransitionTest {
+			c := new(FinalityTestCase)
+			c.Concurrency = 4
+			return c
+		})
+}
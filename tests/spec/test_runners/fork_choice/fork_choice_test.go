@@ -0,0 +1,471 @@
+// The v0.9.3 spec tests do not include fork choice vectors, the first vector releases with the fork choice format
+// target a later spec version, with a different state layout. Instead, these cases follow the fork choice tests
+// of the v0.9.3 pyspec (get_head, on_tick, on_block and on_attestation), run against the fork choice store.
+package fork_choice
+
+import (
+	"errors"
+	. "github.com/protolambda/zrnt/eth2/beacon/attestations"
+	"github.com/protolambda/zrnt/eth2/beacon/randao"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"github.com/protolambda/zrnt/eth2/util/bls"
+	"github.com/protolambda/zrnt/eth2/util/ssz"
+	"testing"
+)
+
+func genesisState(t *testing.T) (*phase0.FullFeaturedState, [][32]byte) {
+	count := uint64(SLOTS_PER_EPOCH) * 8
+	keys := make([][32]byte, count)
+	validators := make([]phase0.KickstartValidatorData, count)
+	for i := range keys {
+		keys[i] = [32]byte{30: byte((i + 1) >> 8), 31: byte(i + 1)}
+		validators[i] = phase0.KickstartValidatorData{
+			Pubkey:                bls.BlsSecretToPubkey(keys[i]),
+			WithdrawalCredentials: Root{1},
+			Balance:               MAX_EFFECTIVE_BALANCE,
+		}
+	}
+	state, err := phase0.KickStartState(Root{123}, 0, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, keys
+}
+
+func copyState(t *testing.T, state *phase0.FullFeaturedState) *phase0.FullFeaturedState {
+	out, err := state.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// Builds and signs an empty block for the next slot, like build_empty_block_for_next_slot,
+// and applies it to the state, like state_transition_and_sign_block.
+func nextBlock(t *testing.T, state *phase0.FullFeaturedState, keys [][32]byte, graffiti Root) *phase0.SignedBeaconBlock {
+	slot := state.Slot + 1
+	pre := copyState(t, state)
+	pre.ProcessSlots(slot)
+	proposer := pre.GetBeaconProposerIndex(slot)
+	epoch := slot.ToEpoch()
+	block := &phase0.SignedBeaconBlock{}
+	block.Message.Slot = slot
+	block.Message.ParentRoot = pre.HeadRoot()
+	block.Message.Body.RandaoReveal = bls.BlsSign(keys[proposer],
+		ssz.HashTreeRoot(epoch, randao.RandaoEpochSSZ), pre.GetDomain(DOMAIN_RANDAO, epoch))
+	block.Message.Body.Eth1Data = pre.Eth1Data
+	block.Message.Body.Graffiti = graffiti
+	if err := (&phase0.BlockProcessFeature{Block: block, Meta: pre}).Process(); err != nil {
+		t.Fatal(err)
+	}
+	block.Message.StateRoot = pre.StateRoot()
+	block.Signature = bls.BlsSign(keys[proposer],
+		ssz.HashTreeRoot(&block.Message, phase0.BeaconBlockSSZ), pre.GetDomain(DOMAIN_BEACON_PROPOSER, epoch))
+	if err := state.StateTransition(&phase0.BlockProcessFeature{Block: block, Meta: state}, true); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+// A signed attestation of the first committee at the slot of the state, for the head of the state.
+func attest(t *testing.T, state *phase0.FullFeaturedState, keys [][32]byte) *Attestation {
+	slot := state.Slot
+	epoch := slot.ToEpoch()
+	head := state.HeadRoot()
+	target := Checkpoint{Epoch: epoch, Root: head}
+	if start := epoch.GetStartSlot(); start < slot {
+		target.Root = state.GetBlockRootAtSlot(start)
+	}
+	data := AttestationData{
+		Slot:            slot,
+		Index:           0,
+		BeaconBlockRoot: head,
+		Source:          state.CurrentJustifiedCheckpoint,
+		Target:          target,
+	}
+	committee := state.GetBeaconCommittee(slot, 0)
+	bits := make(CommitteeBits, (len(committee)/8)+1)
+	bits.SetBit(uint64(len(committee)), true)
+	root := ssz.HashTreeRoot(&data, AttestationDataSSZ)
+	domain := state.GetDomain(DOMAIN_BEACON_ATTESTER, epoch)
+	sigs := make([]BLSSignature, 0, len(committee))
+	for i, v := range committee {
+		bits.SetBit(uint64(i), true)
+		sigs = append(sigs, bls.BlsSign(keys[v], root, domain))
+	}
+	sig, err := bls.BlsAggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Attestation{AggregationBits: bits, Data: data, Signature: sig}
+}
+
+func blockRoot(block *phase0.SignedBeaconBlock) Root {
+	return ssz.HashTreeRoot(&block.Message, phase0.BeaconBlockSSZ)
+}
+
+func slotTime(store *forkchoice.Store, slot Slot) Timestamp {
+	return store.GenesisTime + Timestamp(slot)*SECONDS_PER_SLOT
+}
+
+// Like add_block_to_store: ticks to the slot of the block if necessary, and adds the block.
+func addBlock(t *testing.T, store *forkchoice.Store, block *phase0.SignedBeaconBlock) {
+	if blockTime := slotTime(store, block.Message.Slot); store.Time < blockTime {
+		store.OnTick(blockTime)
+	}
+	if err := store.OnBlock(block); err != nil {
+		t.Fatalf("block at slot %d rejected: %v", block.Message.Slot, err)
+	}
+}
+
+// Like add_attestation_to_store: ticks an epoch past the attested block if necessary, and adds the attestation.
+func addAttestation(t *testing.T, store *forkchoice.Store, att *Attestation) {
+	block := store.Blocks[att.Data.BeaconBlockRoot]
+	if nextEpochTime := slotTime(store, block.Slot+SLOTS_PER_EPOCH); store.Time < nextEpochTime {
+		store.OnTick(nextEpochTime)
+	}
+	if err := store.OnAttestation(att); err != nil {
+		t.Fatalf("attestation rejected: %v", err)
+	}
+}
+
+func expectHead(t *testing.T, store *forkchoice.Store, expected Root) {
+	t.Helper()
+	head, err := store.GetHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != expected {
+		t.Errorf("expected head %x, got %x", expected, head)
+	}
+}
+
+func TestGetHead(t *testing.T) {
+	t.Run("genesis", func(t *testing.T) {
+		state, _ := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		genesisBlock := phase0.BeaconBlock{StateRoot: state.StateRoot()}
+		expectHead(t, store, ssz.HashTreeRoot(&genesisBlock, phase0.BeaconBlockSSZ))
+	})
+
+	t.Run("chain_no_attestations", func(t *testing.T) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		addBlock(t, store, nextBlock(t, state, keys, Root{}))
+		block2 := nextBlock(t, state, keys, Root{})
+		addBlock(t, store, block2)
+		expectHead(t, store, blockRoot(block2))
+	})
+
+	t.Run("split_tie_breaker_no_attestations", func(t *testing.T) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		block1 := nextBlock(t, copyState(t, state), keys, Root{})
+		block2 := nextBlock(t, copyState(t, state), keys, Root{0x42})
+		addBlock(t, store, block1)
+		addBlock(t, store, block2)
+		highest := blockRoot(block1)
+		if root2 := blockRoot(block2); rootLess(highest, root2) {
+			highest = root2
+		}
+		expectHead(t, store, highest)
+	})
+
+	t.Run("shorter_chain_but_heavier_weight", func(t *testing.T) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		long := copyState(t, state)
+		for i := 0; i < 3; i++ {
+			addBlock(t, store, nextBlock(t, long, keys, Root{}))
+		}
+		short := copyState(t, state)
+		shortBlock := nextBlock(t, short, keys, Root{0x42})
+		addBlock(t, store, shortBlock)
+		addAttestation(t, store, attest(t, short, keys))
+		expectHead(t, store, blockRoot(shortBlock))
+	})
+}
+
+func rootLess(a Root, b Root) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func TestOnTick(t *testing.T) {
+	secondsPerEpoch := Timestamp(SLOTS_PER_EPOCH) * SECONDS_PER_SLOT
+	cases := []struct {
+		name string
+		// prepares the store, and returns the time to tick to
+		prepare func(store *forkchoice.Store) Timestamp
+		update  bool
+	}{
+		{"basic", func(store *forkchoice.Store) Timestamp {
+			return store.Time + 1
+		}, false},
+		{"update_justified_single", func(store *forkchoice.Store) Timestamp {
+			store.BestJustifiedCheckpoint = Checkpoint{Epoch: store.JustifiedCheckpoint.Epoch + 1, Root: Root{0x55}}
+			return store.Time + secondsPerEpoch
+		}, true},
+		{"no_update_same_slot_at_epoch_boundary", func(store *forkchoice.Store) Timestamp {
+			store.BestJustifiedCheckpoint = Checkpoint{Epoch: store.JustifiedCheckpoint.Epoch + 1, Root: Root{0x55}}
+			store.Time = secondsPerEpoch
+			return store.Time + 1
+		}, false},
+		{"no_update_not_epoch_boundary", func(store *forkchoice.Store) Timestamp {
+			store.BestJustifiedCheckpoint = Checkpoint{Epoch: store.JustifiedCheckpoint.Epoch + 1, Root: Root{0x55}}
+			return store.Time + SECONDS_PER_SLOT
+		}, false},
+		{"no_update_new_justified_equal_epoch", func(store *forkchoice.Store) Timestamp {
+			store.BestJustifiedCheckpoint = Checkpoint{Epoch: store.JustifiedCheckpoint.Epoch + 1, Root: Root{0x55}}
+			store.JustifiedCheckpoint = Checkpoint{Epoch: store.BestJustifiedCheckpoint.Epoch, Root: Root{0x44}}
+			return store.Time + secondsPerEpoch
+		}, false},
+		{"no_update_new_justified_later_epoch", func(store *forkchoice.Store) Timestamp {
+			store.BestJustifiedCheckpoint = Checkpoint{Epoch: store.JustifiedCheckpoint.Epoch + 1, Root: Root{0x55}}
+			store.JustifiedCheckpoint = Checkpoint{Epoch: store.BestJustifiedCheckpoint.Epoch + 1, Root: Root{0x44}}
+			return store.Time + secondsPerEpoch
+		}, false},
+	}
+	state, _ := genesisState(t)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := forkchoice.NewStore(copyState(t, state))
+			time := c.prepare(store)
+			previous := store.JustifiedCheckpoint
+			store.OnTick(time)
+			if store.Time != time {
+				t.Errorf("expected time %d, got %d", time, store.Time)
+			}
+			if c.update && store.JustifiedCheckpoint != store.BestJustifiedCheckpoint {
+				t.Errorf("expected justified checkpoint to be updated to %v, got %v",
+					store.BestJustifiedCheckpoint, store.JustifiedCheckpoint)
+			}
+			if !c.update && store.JustifiedCheckpoint != previous {
+				t.Errorf("expected justified checkpoint to stay %v, got %v", previous, store.JustifiedCheckpoint)
+			}
+		})
+	}
+}
+
+func TestOnBlock(t *testing.T) {
+	t.Run("basic", func(t *testing.T) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		store.OnTick(100)
+		block := nextBlock(t, state, keys, Root{})
+		if err := store.OnBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		// a block of the next epoch
+		store.OnTick(100 + Timestamp(SLOTS_PER_EPOCH)*SECONDS_PER_SLOT)
+		state.ProcessSlots(state.Slot + SLOTS_PER_EPOCH - 1)
+		next := nextBlock(t, state, keys, Root{})
+		if err := store.OnBlock(next); err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range []*phase0.SignedBeaconBlock{block, next} {
+			if _, ok := store.Blocks[blockRoot(b)]; !ok {
+				t.Errorf("block at slot %d is not in the store", b.Message.Slot)
+			}
+		}
+	})
+
+	t.Run("future_block", func(t *testing.T) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		// the store is still at genesis time
+		if err := store.OnBlock(nextBlock(t, state, keys, Root{})); err != forkchoice.ErrFutureBlock {
+			t.Errorf("expected future block to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("bad_parent_root", func(t *testing.T) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		store.OnTick(100)
+		block := nextBlock(t, state, keys, Root{})
+		block.Message.ParentRoot = Root{0x45}
+		if err := store.OnBlock(block); !errors.Is(err, forkchoice.ErrUnknownBlock) {
+			t.Errorf("expected block with unknown parent to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("before_finalized", func(t *testing.T) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		store.OnTick(100)
+		store.FinalizedCheckpoint = Checkpoint{Epoch: store.FinalizedCheckpoint.Epoch + 2, Root: store.FinalizedCheckpoint.Root}
+		if err := store.OnBlock(nextBlock(t, state, keys, Root{})); err != forkchoice.ErrNotFinalized {
+			t.Errorf("expected block before finalized slot to be rejected, got %v", err)
+		}
+	})
+
+	// Adds a block in the epoch after genesis, ticks the store into the epoch after that,
+	// and adds a block on top of a mocked parent state with a newer justified checkpoint.
+	mockJustified := func(t *testing.T, afterSafeSlots Slot) (*forkchoice.Store, Checkpoint) {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		state.ProcessSlots((GENESIS_EPOCH + 1).GetStartSlot())
+		block := nextBlock(t, state, keys, Root{})
+		addBlock(t, store, block)
+		store.OnTick(slotTime(store, (GENESIS_EPOCH+2).GetStartSlot()+afterSafeSlots))
+
+		justState := store.BlockStates[blockRoot(block)]
+		// fill in the state root of the latest header before mocking, the block remains the head of the state.
+		justState.LatestBlockHeader.StateRoot = justState.StateRoot()
+		newJustified := Checkpoint{Epoch: justState.CurrentJustifiedCheckpoint.Epoch + 1, Root: Root{0x77}}
+		justState.CurrentJustifiedCheckpoint = newJustified
+		if err := store.OnBlock(nextBlock(t, copyState(t, justState), keys, Root{})); err != nil {
+			t.Fatal(err)
+		}
+		return store, newJustified
+	}
+
+	t.Run("update_justified_checkpoint_within_safe_slots", func(t *testing.T) {
+		store, newJustified := mockJustified(t, 0)
+		if store.CurrentSlot()%SLOTS_PER_EPOCH >= SAFE_SLOTS_TO_UPDATE_JUSTIFIED {
+			t.Fatal("expected to be within the safe slots")
+		}
+		if store.JustifiedCheckpoint != newJustified {
+			t.Errorf("expected justified checkpoint %v, got %v", newJustified, store.JustifiedCheckpoint)
+		}
+	})
+
+	t.Run("outside_safe_slots_and_better_justified", func(t *testing.T) {
+		store, newJustified := mockJustified(t, SAFE_SLOTS_TO_UPDATE_JUSTIFIED)
+		if store.CurrentSlot()%SLOTS_PER_EPOCH < SAFE_SLOTS_TO_UPDATE_JUSTIFIED {
+			t.Fatal("expected to be outside the safe slots")
+		}
+		if store.JustifiedCheckpoint == newJustified {
+			t.Error("justified checkpoint was updated outside of the safe slots")
+		}
+		if store.BestJustifiedCheckpoint != newJustified {
+			t.Errorf("expected best justified checkpoint %v, got %v", newJustified, store.BestJustifiedCheckpoint)
+		}
+		// adopted at the start of the next epoch
+		store.OnTick(slotTime(store, (store.CurrentSlot().ToEpoch() + 1).GetStartSlot()))
+		if store.JustifiedCheckpoint != newJustified {
+			t.Errorf("expected justified checkpoint %v at the next epoch, got %v", newJustified, store.JustifiedCheckpoint)
+		}
+	})
+}
+
+func TestOnAttestation(t *testing.T) {
+	secondsPerEpoch := Timestamp(SLOTS_PER_EPOCH) * SECONDS_PER_SLOT
+
+	type testChain struct {
+		store *forkchoice.Store
+		state *phase0.FullFeaturedState
+		keys  [][32]byte
+	}
+	// Ticks the store to the given time, and adds a block at the slot after genesis.
+	prepare := func(t *testing.T, time Timestamp) *testChain {
+		state, keys := genesisState(t)
+		store := forkchoice.NewStore(copyState(t, state))
+		store.OnTick(time)
+		if err := store.OnBlock(nextBlock(t, state, keys, Root{})); err != nil {
+			t.Fatal(err)
+		}
+		return &testChain{store: store, state: state, keys: keys}
+	}
+	expectValid := func(t *testing.T, c *testChain, att *Attestation) {
+		t.Helper()
+		if err := c.store.OnAttestation(att); err != nil {
+			t.Fatal(err)
+		}
+		committee := c.state.GetBeaconCommittee(att.Data.Slot, att.Data.Index)
+		expected := forkchoice.LatestMessage{Epoch: att.Data.Target.Epoch, Root: att.Data.BeaconBlockRoot}
+		if msg := c.store.LatestMessages[committee[0]]; msg != expected {
+			t.Errorf("expected latest message %v, got %v", expected, msg)
+		}
+	}
+	expectInvalid := func(t *testing.T, c *testChain, att *Attestation) {
+		t.Helper()
+		if err := c.store.OnAttestation(att); err == nil {
+			t.Error("expected attestation to be rejected")
+		}
+	}
+
+	t.Run("current_epoch", func(t *testing.T) {
+		c := prepare(t, SECONDS_PER_SLOT*2)
+		att := attest(t, c.state, c.keys)
+		if c.store.CurrentSlot().ToEpoch() != GENESIS_EPOCH || att.Data.Target.Epoch != GENESIS_EPOCH {
+			t.Fatal("expected attestation and store in the genesis epoch")
+		}
+		expectValid(t, c, att)
+	})
+
+	t.Run("previous_epoch", func(t *testing.T) {
+		c := prepare(t, secondsPerEpoch)
+		if c.store.CurrentSlot().ToEpoch() != GENESIS_EPOCH+1 {
+			t.Fatal("expected store in the epoch after genesis")
+		}
+		expectValid(t, c, attest(t, c.state, c.keys))
+	})
+
+	t.Run("past_epoch", func(t *testing.T) {
+		c := prepare(t, secondsPerEpoch*2)
+		expectInvalid(t, c, attest(t, c.state, c.keys))
+	})
+
+	t.Run("mismatched_target_and_slot", func(t *testing.T) {
+		c := prepare(t, secondsPerEpoch)
+		att := attest(t, c.state, c.keys)
+		att.Data.Target.Epoch += 1
+		expectInvalid(t, c, att)
+	})
+
+	t.Run("target_not_in_store", func(t *testing.T) {
+		c := prepare(t, secondsPerEpoch)
+		// the block is not added to the store
+		nextBlock(t, c.state, c.keys, Root{})
+		att := attest(t, c.state, c.keys)
+		att.Data.Target.Root = c.state.HeadRoot()
+		expectInvalid(t, c, att)
+	})
+
+	t.Run("beacon_block_not_in_store", func(t *testing.T) {
+		c := prepare(t, secondsPerEpoch)
+		// the block is not added to the store
+		nextBlock(t, c.state, c.keys, Root{})
+		expectInvalid(t, c, attest(t, c.state, c.keys))
+	})
+
+	t.Run("future_epoch", func(t *testing.T) {
+		c := prepare(t, SECONDS_PER_SLOT*3)
+		// move the state forward, but not the store
+		c.state.ProcessSlots((GENESIS_EPOCH + 1).GetStartSlot())
+		expectInvalid(t, c, attest(t, c.state, c.keys))
+	})
+
+	t.Run("same_slot", func(t *testing.T) {
+		// attestations only count from the slot after their slot
+		c := prepare(t, SECONDS_PER_SLOT)
+		if err := c.store.OnAttestation(attest(t, c.state, c.keys)); err != forkchoice.ErrFutureAttestation {
+			t.Errorf("expected attestation of the current slot to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("invalid_committee_index", func(t *testing.T) {
+		c := prepare(t, SECONDS_PER_SLOT*3)
+		att := attest(t, c.state, c.keys)
+		att.Data.Index = ^CommitteeIndex(0)
+		expectInvalid(t, c, att)
+	})
+
+	t.Run("invalid_signature", func(t *testing.T) {
+		if !bls.BLS_ACTIVE {
+			t.Skip("BLS is disabled")
+		}
+		c := prepare(t, SECONDS_PER_SLOT*3)
+		att := attest(t, c.state, c.keys)
+		att.Signature = BLSSignature{}
+		expectInvalid(t, c, att)
+	})
+}
@@ -49,3 +49,12 @@ func TestBlocks(t *testing.T) {
 	test_util.RunTransitionTest(t, "sanity", "blocks",
 		func() test_util.TransitionTest { return new(BlocksTestCase) })
 }
+
+func TestBlocksParallel(t *testing.T) {
+	test_util.RunTransitionTest(t, "sanity", "blocks",
+		func() test_util.TransitionTest {
+			c := new(BlocksTestCase)
+			c.Concurrency = 4
+			return c
+		})
+}
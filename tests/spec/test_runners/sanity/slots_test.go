@@ -30,3 +30,12 @@ func TestSlots(t *testing.T) {
 	test_util.RunTransitionTest(t, "sanity", "slots",
 		func() test_util.TransitionTest { return new(SlotsTestCase) })
 }
+
+func TestSlotsParallel(t *testing.T) {
+	test_util.RunTransitionTest(t, "sanity", "slots",
+		func() test_util.TransitionTest {
+			c := new(SlotsTestCase)
+			c.Concurrency = 4
+			return c
+		})
+}
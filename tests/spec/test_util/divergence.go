@@ -0,0 +1,159 @@
+package test_util
+
+import (
+	"errors"
+	"fmt"
+	"github.com/protolambda/messagediff"
+	. "github.com/protolambda/zrnt/eth2/core"
+	"github.com/protolambda/zrnt/eth2/phase0"
+	"reflect"
+	"strings"
+)
+
+// Divergence describes the first field of a state that differs from the expected state.
+type Divergence struct {
+	// Path to the diverging field, e.g. "Validators[123].EffectiveBalance".
+	// Embedded sub-states are left out of the path.
+	Path string
+	// The expected value, nil if the field is missing in the expected state.
+	Expected interface{}
+	// The actual value, nil if the field is missing in the actual state.
+	Got interface{}
+	// sort key: the field and element indices along the path.
+	key []int
+}
+
+func formatValue(v interface{}, bytes bool) string {
+	if v == nil {
+		return "<missing>"
+	}
+	if bytes {
+		return fmt.Sprintf("%x", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func (d *Divergence) String() string {
+	_, isBytes := d.Expected.([]byte)
+	if !isBytes && d.Expected != nil {
+		isBytes = reflect.TypeOf(d.Expected).Kind() == reflect.Array &&
+			reflect.TypeOf(d.Expected).Elem().Kind() == reflect.Uint8
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", d.Path,
+		formatValue(d.Expected, isBytes), formatValue(d.Got, isBytes))
+}
+
+// Before returns true if the divergence is located before the other one, in field and element order.
+func (d *Divergence) Before(other *Divergence) bool {
+	for i := 0; i < len(d.key) && i < len(other.key); i++ {
+		if d.key[i] != other.key[i] {
+			return d.key[i] < other.key[i]
+		}
+	}
+	return len(d.key) < len(other.key)
+}
+
+// Resolves a diff path against the given root type,
+// to get a readable path and a key to order paths by field and element order.
+func resolvePath(typ reflect.Type, path messagediff.Path) (string, []int) {
+	var name strings.Builder
+	key := make([]int, 0, len(path))
+	for _, node := range path {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		switch n := node.(type) {
+		case messagediff.StructField:
+			field, ok := typ.FieldByName(string(n))
+			if !ok {
+				// not expected to happen, the path is derived from the same type.
+				name.WriteString(n.String())
+				continue
+			}
+			key = append(key, field.Index...)
+			if !field.Anonymous {
+				if name.Len() > 0 {
+					name.WriteString(".")
+				}
+				name.WriteString(field.Name)
+			}
+			typ = field.Type
+		case messagediff.SliceIndex:
+			key = append(key, int(n))
+			name.WriteString(n.String())
+			typ = typ.Elem()
+		default:
+			// BeaconState has no maps, keep the node as-is, without ordering.
+			key = append(key, 0)
+			name.WriteString(node.String())
+			typ = typ.Elem()
+		}
+	}
+	return name.String(), key
+}
+
+// FirstDivergence compares the given state against the expected state,
+// and returns the first diverging field, in field and element order. Nil if the states are equal.
+func FirstDivergence(expected *phase0.BeaconState, got *phase0.BeaconState) *Divergence {
+	diff, equal := messagediff.DeepDiff(expected, got, messagediff.SliceWeakEmptyOption{})
+	if equal {
+		return nil
+	}
+	typ := reflect.TypeOf(expected)
+	var first *Divergence
+	consider := func(path *messagediff.Path, expected interface{}, got interface{}) {
+		name, key := resolvePath(typ, *path)
+		d := &Divergence{Path: name, Expected: expected, Got: got, key: key}
+		if first == nil || d.Before(first) {
+			first = d
+		}
+	}
+	for path, v := range diff.Added {
+		consider(path, nil, v)
+	}
+	for path, v := range diff.Removed {
+		consider(path, v, nil)
+	}
+	for path, v := range diff.Modified {
+		switch m := v.(type) {
+		case *messagediff.Different:
+			consider(path, m.From, m.To)
+		case *messagediff.BytesDifferent:
+			consider(path, m.From, m.To)
+		}
+	}
+	return first
+}
+
+// Transition is an arbitrary transition of a state, e.g. processing slots or blocks.
+type Transition func(state *phase0.FullFeaturedState) error
+
+// BlocksTransition creates a transition that applies the given blocks in order, with validation of the signatures.
+// A state root that does not match the block is not an error: the blocks are processed regardless,
+// to find where the resulting state diverges.
+func BlocksTransition(blocks ...*phase0.SignedBeaconBlock) Transition {
+	return func(state *phase0.FullFeaturedState) error {
+		for i, b := range blocks {
+			err := state.StateTransition(&phase0.BlockProcessFeature{Block: b, Meta: state}, true)
+			// the state root is verified after the block is fully processed, the state is complete.
+			if err != nil && !errors.Is(err, BlockInvalidStateRoot) {
+				return fmt.Errorf("failed to process block %d (slot %d): %v", i, b.Message.Slot, err)
+			}
+		}
+		return nil
+	}
+}
+
+// DiffTransition runs the transition on a copy of the pre-state,
+// and reports the first field where the result diverges from the expected post-state.
+// Returns a nil divergence if the result matches the expected post-state.
+func DiffTransition(pre *phase0.BeaconState, expected *phase0.BeaconState, transition Transition) (*Divergence, error) {
+	state, err := phase0.NewFullFeaturedState(pre).Copy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy pre-state: %v", err)
+	}
+	if err := transition(state); err != nil {
+		return nil, err
+	}
+	return FirstDivergence(expected, state.BeaconState), nil
+}
@@ -10,6 +10,8 @@ import (
 type BaseTransitionTest struct {
 	Pre  *phase0.BeaconState
 	Post *phase0.BeaconState
+	// Amount of workers to process the transition with, sequential if 1 or less.
+	Concurrency int
 }
 
 func (c *BaseTransitionTest) ExpectingFailure() bool {
@@ -18,6 +20,7 @@ func (c *BaseTransitionTest) ExpectingFailure() bool {
 
 func (c *BaseTransitionTest) Prepare() *phase0.FullFeaturedState {
 	state := phase0.NewFullFeaturedState(c.Pre)
+	state.Concurrency = c.Concurrency
 	state.LoadPrecomputedData()
 	return state
 }
@@ -40,7 +43,7 @@ func (c *BaseTransitionTest) Check(t *testing.T) {
 	if c.ExpectingFailure() {
 		t.Errorf("was expecting failure, but no error was raised")
 	} else if diff, equal := messagediff.PrettyDiff(c.Pre, c.Post, messagediff.SliceWeakEmptyOption{}); !equal {
-		t.Errorf("end result does not match expectation!\n%s", diff)
+		t.Errorf("end result does not match expectation! first divergence: %s\n%s", FirstDivergence(c.Post, c.Pre), diff)
 	}
 }
 